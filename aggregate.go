@@ -0,0 +1,148 @@
+package structscanner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// aggregator merges elements scanned from successive rows into a slice, so
+// that rows from a one-to-many join produce a single element per distinct
+// parent, holding the distinct elements of each of its collections.
+//
+// Elements are identified by their key fields (see structLayout.findKeyFields),
+// and may be merged whether or not their rows are consecutive.
+type aggregator struct {
+	layout *structLayout
+	root   *aggregateIndex
+}
+
+// aggregateIndex records the position of each distinct element within a slice,
+// along with the indices of the collections within each element.
+type aggregateIndex struct {
+	positions map[string]int
+	children  []map[*collection]*aggregateIndex
+}
+
+func newAggregator(layout *structLayout) *aggregator {
+	return &aggregator{
+		layout: layout,
+		root:   newAggregateIndex(),
+	}
+}
+
+func newAggregateIndex() *aggregateIndex {
+	return &aggregateIndex{
+		positions: make(map[string]int),
+	}
+}
+
+// add merges elem, scanned from a single row, into sliceValue. A nil pointer
+// element, from a row whose columns are all NULL, is skipped in the same way as
+// a collection element whose columns are all NULL.
+func (a *aggregator) add(sliceValue reflect.Value, elem reflect.Value) {
+	if isNilPtr(elem) {
+		return
+	}
+
+	a.merge(a.root, nil, sliceValue, elem)
+}
+
+// merge merges elem, an element of coll (or of the root struct if coll is
+// nil), into sliceValue. If an element with the same key already exists, the
+// collections of elem are merged into it; otherwise elem is appended.
+func (a *aggregator) merge(index *aggregateIndex, coll *collection, sliceValue reflect.Value, elem reflect.Value) {
+	childCollections := a.layout.childCollections(coll)
+	key := a.key(coll, elem)
+
+	pending := make([]reflect.Value, len(childCollections))
+
+	pos, ok := index.positions[key]
+	if ok {
+		for i, c := range childCollections {
+			pending[i] = fieldByIndices(indirect(elem), c.Indices)
+		}
+
+	} else {
+		// Detach the collections from the new element, so that their elements
+		// are merged into it in the same way as for an existing element
+		for i, c := range childCollections {
+			childSlice := fieldByIndices(indirect(elem), c.Indices)
+			pending[i] = reflect.ValueOf(childSlice.Interface())
+			childSlice.Set(reflect.Zero(childSlice.Type()))
+		}
+
+		sliceValue.Set(reflect.Append(sliceValue, elem))
+		pos = sliceValue.Len() - 1
+
+		index.positions[key] = pos
+		index.children = append(index.children, make(map[*collection]*aggregateIndex))
+	}
+
+	target := indirect(sliceValue.Index(pos))
+
+	for i, c := range childCollections {
+		childIndex := index.children[pos][c]
+		if childIndex == nil {
+			childIndex = newAggregateIndex()
+			index.children[pos][c] = childIndex
+		}
+
+		for j := 0; j < pending[i].Len(); j++ {
+			a.merge(childIndex, c, fieldByIndices(target, c.Indices), pending[i].Index(j))
+		}
+	}
+}
+
+// key returns a string identifying elem, an element of coll, from the values
+// of its key fields.
+func (a *aggregator) key(coll *collection, elem reflect.Value) string {
+	var b strings.Builder
+
+	for _, f := range a.layout.keyFields[coll] {
+		v, ok := valueByIndices(indirect(elem), f.Indices)
+		if ok && v.Kind() == reflect.Ptr {
+			ok = !v.IsNil()
+			if ok {
+				v = v.Elem()
+			}
+		}
+
+		if ok {
+			fmt.Fprintf(&b, "%#v\x00", v.Interface())
+		} else {
+			b.WriteString("nil\x00")
+		}
+	}
+
+	return b.String()
+}
+
+// indirect returns the value v points to if it is a pointer, or v otherwise.
+func indirect(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		return v.Elem()
+	}
+	return v
+}
+
+// valueByIndices returns the field of v at the path given by indices, or false
+// if v is invalid or a nil pointer is encountered along the way.
+func valueByIndices(v reflect.Value, indices []int) (reflect.Value, bool) {
+	if !v.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	for _, i := range indices {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return v, true
+}
@@ -0,0 +1,107 @@
+package structscanner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// SelectBatches performs a database query and scans its rows into batches of
+// up to n values of type T, which must be a struct, a scalar or a pointer to
+// either. fn is called with each batch as it is filled, and with any final
+// partial batch, so memory use is bounded regardless of how many rows the query
+// returns.
+//
+// The slice passed to fn is reused for the next batch, so fn must not retain it
+// or its elements after returning. If fn returns an error, iteration stops, the
+// rows are closed and the error is returned.
+//
+// As with Rows, T must not have collections, as each row is scanned into its
+// own value.
+//
+// Options may be given amongst args, as with Select.
+func SelectBatches[T any](tx Queryer, n int, prefix string, query string, args []interface{}, fn func([]T) error) error {
+	return SelectBatchesContextWith(defaultMapper, context.Background(), queryerWithoutContext{tx}, n, prefix, query, args, fn)
+}
+
+// SelectBatchesContext is like SelectBatches, but performs the query using ctx,
+// and stops scanning between rows with the context's error once ctx is done.
+func SelectBatchesContext[T any](ctx context.Context, tx QueryerContext, n int, prefix string, query string, args []interface{}, fn func([]T) error) error {
+	return SelectBatchesContextWith(defaultMapper, ctx, tx, n, prefix, query, args, fn)
+}
+
+// SelectBatchesWith is like SelectBatches, but uses the configuration of m.
+func SelectBatchesWith[T any](m *Mapper, tx Queryer, n int, prefix string, query string, args []interface{}, fn func([]T) error) error {
+	return SelectBatchesContextWith(m, context.Background(), queryerWithoutContext{tx}, n, prefix, query, args, fn)
+}
+
+// SelectBatchesContextWith is like SelectBatchesContext, but uses the
+// configuration of m.
+func SelectBatchesContextWith[T any](m *Mapper, ctx context.Context, tx QueryerContext, n int, prefix string, query string, args []interface{}, fn func([]T) error) (err error) {
+	if n <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", n)
+	}
+
+	elemType := reflect.TypeOf((*T)(nil)).Elem()
+	if !isRowType(elemType) {
+		return fmt.Errorf("%w: element must be struct or scalar, got %v", ErrUnsupportedDestination, elemType)
+	}
+
+	opts, args := splitOptions(args)
+	c := m.config(prefix, opts)
+
+	s := m.forConfig(elemType, c)
+	if s.layout.err != nil {
+		return s.layout.err
+	}
+
+	if len(s.layout.collections) > 0 {
+		return fmt.Errorf("%w: element with collections cannot be scanned one row at a time, got %v", ErrUnsupportedDestination, elemType)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	scanned := 0
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = rowsError(scanned, closeErr)
+		}
+	}()
+
+	batch := make([]T, 0, n)
+
+	for (c.maxRows <= 0 || scanned < c.maxRows) && rows.Next() {
+		var zero T
+		batch = append(batch, zero)
+
+		err := s.ScanContext(ctx, rows, &batch[len(batch)-1])
+		if err != nil {
+			return err
+		}
+
+		scanned++
+
+		if len(batch) == n {
+			err := fn(batch)
+			if err != nil {
+				return err
+			}
+
+			batch = batch[:0]
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return rowsError(scanned, err)
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+
+	return nil
+}
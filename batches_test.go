@@ -0,0 +1,172 @@
+package structscanner
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSelectBatches(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	type Fruit struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	t.Run("calls back with full batches and a final partial batch", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits WHERE colour = ?`
+
+		dbMock.
+			ExpectQuery(query).
+			WithArgs("yellow").
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "banana").
+					AddRow(2, "lemon").
+					AddRow(3, "pineapple").
+					AddRow(4, "quince").
+					AddRow(5, "starfruit"),
+			)
+
+		var batchSizes []int
+		var ids []int64
+
+		err := SelectBatches(db, 2, "", query, []interface{}{"yellow"}, func(batch []Fruit) error {
+			batchSizes = append(batchSizes, len(batch))
+			for _, fruit := range batch {
+				ids = append(ids, fruit.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := []int{2, 2, 1}, batchSizes; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected batch sizes %v but got %v", expected, actual)
+		}
+		if expected, actual := []int64{1, 2, 3, 4, 5}, ids; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected IDs %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("stops and closes rows when callback returns an error", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits`
+		callbackErr := errors.New("callback failed")
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "banana").
+					AddRow(2, "lemon").
+					AddRow(3, "pineapple"),
+			).
+			RowsWillBeClosed()
+
+		calls := 0
+
+		err := SelectBatches(db, 1, "", query, nil, func(batch []Fruit) error {
+			calls++
+			return callbackErr
+		})
+
+		if !errors.Is(err, callbackErr) {
+			t.Errorf("Expected callback error but got: %v", err)
+		}
+		if expected, actual := 1, calls; expected != actual {
+			t.Errorf("Expected %d calls but got %d", expected, actual)
+		}
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("does not call back when there are no rows", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}),
+			)
+
+		calls := 0
+
+		err := SelectBatches(db, 10, "", query, nil, func(batch []Fruit) error {
+			calls++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 0, calls; expected != actual {
+			t.Errorf("Expected %d calls but got %d", expected, actual)
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination for elements with collections", func(t *testing.T) {
+
+		type Org struct {
+			ID     int64   `db:"id,pk"`
+			Fruits []Fruit `db:"f"`
+		}
+
+		err := SelectBatches(db, 10, "", `SELECT o.id, f.id, f.name FROM orgs o JOIN fruits f`, nil, func(batch []Org) error {
+			return nil
+		})
+
+		if !errors.Is(err, ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+		}
+	})
+
+	t.Run("uses the configuration of the Mapper with SelectBatchesWith", func(t *testing.T) {
+
+		m := &Mapper{TagName: "sql"}
+
+		type Vegetable struct {
+			Name string `sql:"name"`
+		}
+
+		query := `SELECT name FROM vegetables`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"name"}).
+					AddRow("carrot").
+					AddRow("leek").
+					AddRow("onion"),
+			)
+
+		var names []string
+
+		err := SelectBatchesWith(m, db, 2, "", query, nil, func(batch []Vegetable) error {
+			for _, v := range batch {
+				names = append(names, v.Name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := []string{"carrot", "leek", "onion"}, names; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected names %v but got %v", expected, actual)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package structscanner
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var errInvalidRow = errors.New("invalid row literal")
+
+// compositeField is a field of a struct filled from a Postgres row literal,
+// such as (1,"Alice",t), by the element at the same position.
+type compositeField struct {
+	Name  string
+	Type  reflect.Type
+	Index int
+}
+
+// compositeFields returns the tagged fields of the struct type t, in declared
+// order, checking that each can be converted from an element of a row literal.
+func (sl *structLayout) compositeFields(m *Mapper, t reflect.Type) []compositeField {
+	var fields []compositeField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, tagged := f.Tag.Lookup(m.tagName())
+		if !tagged || tag == "-" {
+			continue
+		}
+
+		if !f.IsExported() {
+			sl.invalid("field %s.%s has a `%s:` tag but is unexported", t.Name(), f.Name, m.tagName())
+			continue
+		}
+
+		if !isStringConvertible(f.Type, m.converters) && !isArrayType(f.Type, m.converters) {
+			sl.invalid("field %s.%s has type %v, which cannot be converted from an element of a row", t.Name(), f.Name, f.Type)
+			continue
+		}
+
+		fields = append(fields, compositeField{
+			Name:  f.Name,
+			Type:  f.Type,
+			Index: i,
+		})
+	}
+
+	return fields
+}
+
+// decodeComposite returns a decodeFunc that parses a Postgres row literal into
+// a value of the struct type t, assigning its elements to fields in order.
+// NULL elements are set to the zero value of the field, and other elements are
+// converted using the converters in cs.
+func decodeComposite(t reflect.Type, fields []compositeField, cs converters) decodeFunc {
+	return func(src reflect.Value) (reflect.Value, error) {
+		elems, err := parseRow(string(src.Bytes()))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		if len(elems) != len(fields) {
+			return reflect.Value{}, fmt.Errorf("%w: %d elements for %d fields of %v", errInvalidRow, len(elems), len(fields), t)
+		}
+
+		v := reflect.New(t).Elem()
+
+		for i, f := range fields {
+			if elems[i] == nil {
+				continue
+			}
+
+			var fieldValue reflect.Value
+			if isArrayType(f.Type, cs) {
+				fieldValue, err = parseArray(*elems[i], f.Type, cs)
+			} else {
+				fieldValue, err = convertString(*elems[i], f.Type, cs)
+			}
+
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+
+			v.Field(f.Index).Set(fieldValue)
+		}
+
+		return v, nil
+	}
+}
+
+// parseRow parses the Postgres row literal s into the text of its elements,
+// with any quoting and escapes removed. NULL elements, which are empty and
+// unquoted, are nil.
+func parseRow(s string) ([]*string, error) {
+	invalid := func(message string, pos int) error {
+		return fmt.Errorf("%w: %s at position %d of %q", errInvalidRow, message, pos, s)
+	}
+
+	if !strings.HasPrefix(s, "(") {
+		return nil, invalid("expected '('", 0)
+	}
+
+	var elems []*string
+	pos := 1
+
+	for {
+		var b strings.Builder
+		quoted, hasText := false, false
+
+	element:
+		for {
+			if pos >= len(s) {
+				return nil, invalid("unterminated row", pos)
+			}
+
+			c := s[pos]
+
+			switch {
+			case c == '\\':
+				pos++
+				if pos >= len(s) {
+					return nil, invalid("unterminated escape", pos)
+				}
+				c = s[pos]
+
+			case c == '"':
+				// A doubled quote within quotes is a literal quote
+				if quoted && pos+1 < len(s) && s[pos+1] == '"' {
+					pos++
+				} else {
+					quoted = !quoted
+					hasText = true
+					pos++
+					continue
+				}
+
+			case !quoted && (c == ',' || c == ')'):
+				break element
+			}
+
+			b.WriteByte(c)
+			hasText = true
+			pos++
+		}
+
+		if hasText {
+			text := b.String()
+			elems = append(elems, &text)
+		} else {
+			elems = append(elems, nil)
+		}
+
+		if s[pos] == ')' {
+			pos++
+			break
+		}
+		pos++
+	}
+
+	if pos != len(s) {
+		return nil, invalid("unexpected text after row", pos)
+	}
+
+	return elems, nil
+}
@@ -0,0 +1,57 @@
+package structscanner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRow(t *testing.T) {
+
+	t.Run("parses row literals", func(t *testing.T) {
+		for literal, expected := range map[string][]interface{}{
+			`()`:                         {nil},
+			`(1,Alice,t)`:                {"1", "Alice", "t"},
+			`(1,,"")`:                    {"1", nil, ""},
+			`("a, b","say ""hi""",c\,d)`: {"a, b", `say "hi"`, "c,d"},
+			`("(nested)","{1,2}")`:       {"(nested)", "{1,2}"},
+			`( spaced ,"x\"y")`:          {" spaced ", `x"y`},
+		} {
+			actual, err := parseRow(literal)
+			if err != nil {
+				t.Errorf("Expected success for %s but got error: %v", literal, err)
+				continue
+			}
+
+			if len(expected) != len(actual) {
+				t.Errorf("Expected %s to have %d elements but got %d", literal, len(expected), len(actual))
+				continue
+			}
+
+			for i := range expected {
+				if expected[i] == nil {
+					if actual[i] != nil {
+						t.Errorf("Expected element %d of %s to be NULL but got %q", i, literal, *actual[i])
+					}
+				} else if actual[i] == nil || *actual[i] != expected[i] {
+					t.Errorf("Expected element %d of %s to be %q but got %v", i, literal, expected[i], actual[i])
+				}
+			}
+		}
+	})
+
+	t.Run("returns errors for invalid literals", func(t *testing.T) {
+		for _, literal := range []string{
+			``,
+			`(`,
+			`(a,b`,
+			`("a)`,
+			`(a)b`,
+			`1,2`,
+		} {
+			_, err := parseRow(literal)
+			if !errors.Is(err, errInvalidRow) {
+				t.Errorf("Expected invalid row error for %s but got: %v", literal, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package structscanner
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// timeLayouts are the layouts accepted when converting strings to time.Time,
+// covering RFC 3339 and the textual form of Postgres timestamps.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// isStringConvertible reports whether convertString can convert strings to
+// values of type t, using the converters in cs if there is one for t.
+func isStringConvertible(t reflect.Type, cs converters) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if _, ok := cs[t]; ok {
+		return true
+	}
+
+	if reflect.PointerTo(t).Implements(scannerInterface) || t == timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// convertString converts s, the textual form of a value such as an element of
+// a delimited string, to a value of type t. If there is a converter for t in
+// cs it is passed the string; otherwise, types implementing sql.Scanner are
+// passed the string to scan.
+func convertString(s string, t reflect.Type, cs converters) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		v, err := convertString(s, t.Elem(), cs)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(v)
+		return ptr, nil
+	}
+
+	if convert, ok := cs[t]; ok {
+		return convert(reflect.ValueOf(s))
+	}
+
+	v := reflect.New(t).Elem()
+
+	if scanner, ok := v.Addr().Interface().(sql.Scanner); ok {
+		if err := scanner.Scan(s); err != nil {
+			return reflect.Value{}, fmt.Errorf("converting %q to %v: %w", s, t, err)
+		}
+		return v, nil
+	}
+
+	var err error
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, t.Bits())
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, t.Bits())
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		n, err = strconv.ParseFloat(s, t.Bits())
+		v.SetFloat(n)
+
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+
+	case reflect.Struct:
+		var tm time.Time
+		tm, err = parseTime(s)
+		v.Set(reflect.ValueOf(tm))
+	}
+
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("converting %q to %v: %w", s, t, err)
+	}
+
+	return v, nil
+}
+
+// parseTime parses s using the first of timeLayouts that matches it.
+func parseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,55 @@
+package structscanner
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// converters maps types to the converters registered for them with
+// RegisterConverter.
+type converters map[reflect.Type]decodeFunc
+
+// RegisterConverter registers fn with m to convert values from the database to
+// values of type T. Fields of type T, or of type *T, are then set to the
+// result of calling fn with the value returned by the driver for the column,
+// rather than being scanned into directly. This allows types that cannot
+// implement sql.Scanner, such as those from other packages, to be mapped, or
+// values to be mapped in a different way, such as "Y" and "N" to a bool.
+// Values of type T are also scanned from a single column using fn when T is
+// the destination type itself, such as with Query[T], even if T is a struct.
+//
+// If m is nil, fn is registered with the default Mapper used by the
+// package-level functions, such as Select and Query.
+//
+// Converters are also used for the values of maps with the `prefixmap` tag
+// option, and for the elements of slices filled with the `split=` option or
+// from Postgres arrays, and of structs with the `composite` option. For these
+// elements fn is passed the element's text as a string. Fields with the `rest`
+// option hold the values returned by the driver without conversion.
+//
+// fn is not called for NULL values, which result in the zero value of the
+// field. Errors returned by fn are returned as a MappingError. Tag options
+// that decode a column, such as `json`, take precedence over converters.
+//
+// Like the Mapper's fields, converters must not be registered once the Mapper
+// is in use, so they are best registered from an init function. Registering a
+// converter for a type replaces any existing converter for it.
+func RegisterConverter[T any](m *Mapper, fn func(src any) (T, error)) {
+	if m == nil {
+		m = defaultMapper
+	}
+
+	t := reflect.TypeFor[T]()
+
+	if m.converters == nil {
+		m.converters = make(converters)
+	}
+
+	m.converters[t] = func(src reflect.Value) (reflect.Value, error) {
+		v, err := fn(src.Interface())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("converting to %v: %w", t, err)
+		}
+		return reflect.ValueOf(&v).Elem(), nil
+	}
+}
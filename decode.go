@@ -0,0 +1,108 @@
+package structscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// decodeFunc converts a non-NULL value scanned from a column to the type of a
+// field.
+type decodeFunc func(src reflect.Value) (reflect.Value, error)
+
+// decoder returns the scan type and decode function for a field whose values
+// are decoded from the column according to its tag options or type, such as
+// types with a converter registered with the Mapper and slices filled from
+// Postgres arrays, or a nil decode function if the field is scanned into
+// directly.
+func (sl *structLayout) decoder(m *Mapper, t reflect.Type, f reflect.StructField, fieldType reflect.Type, opts tagOptions) (reflect.Type, decodeFunc) {
+	split := opts.values("split")
+
+	decoders := 0
+	for _, has := range []bool{opts.has("json"), len(split) > 0, opts.has("composite")} {
+		if has {
+			decoders++
+		}
+	}
+
+	if decoders > 1 {
+		sl.invalid("field %s.%s has more than one of the `json`, `split=` and `composite` options", t.Name(), f.Name)
+		return nil, nil
+	}
+
+	if opts.has("json") {
+		return bytesType, decodeJSON(fieldType)
+	}
+
+	if len(split) > 0 {
+		if len(split) > 1 || split[0] == "" {
+			sl.invalid("field %s.%s must have a single non-empty `split=` separator", t.Name(), f.Name)
+			return nil, nil
+		}
+
+		if fieldType.Kind() != reflect.Slice || !isStringConvertible(fieldType.Elem(), m.converters) {
+			sl.invalid("field %s.%s has the `split=` option but is not a slice of a convertible type", t.Name(), f.Name)
+			return nil, nil
+		}
+
+		return bytesType, decodeSplit(fieldType, split[0], m.converters)
+	}
+
+	if opts.has("composite") {
+		if fieldType.Kind() != reflect.Struct || isScalar(fieldType) {
+			sl.invalid("field %s.%s has the `composite` option but is not a struct", t.Name(), f.Name)
+			return nil, nil
+		}
+
+		return bytesType, decodeComposite(fieldType, sl.compositeFields(m, fieldType), m.converters)
+	}
+
+	if convert, ok := m.converters[fieldType]; ok {
+		return reflect.TypeFor[any](), convert
+	}
+
+	if isArrayType(fieldType, m.converters) {
+		return bytesType, decodeArray(fieldType, m.converters)
+	}
+
+	return nil, nil
+}
+
+// decodeSplit returns a decodeFunc that splits a string on sep, converting each
+// element to the element type of the slice type t using the converters in cs.
+// Empty strings produce a nil slice.
+func decodeSplit(t reflect.Type, sep string, cs converters) decodeFunc {
+	return func(src reflect.Value) (reflect.Value, error) {
+		s := string(src.Bytes())
+		if s == "" {
+			return reflect.Zero(t), nil
+		}
+
+		parts := strings.Split(s, sep)
+		v := reflect.MakeSlice(t, len(parts), len(parts))
+
+		for i, part := range parts {
+			elem, err := convertString(part, t.Elem(), cs)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(elem)
+		}
+
+		return v, nil
+	}
+}
+
+// decodeJSON returns a decodeFunc that unmarshals JSON into a value of type t.
+func decodeJSON(t reflect.Type) decodeFunc {
+	return func(src reflect.Value) (reflect.Value, error) {
+		v := reflect.New(t)
+		if err := json.Unmarshal(src.Bytes(), v.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("decoding JSON: %w", err)
+		}
+		return v.Elem(), nil
+	}
+}
@@ -0,0 +1,65 @@
+package structscanner
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUnsupportedDestination is returned when a destination is not of a kind
+// that can be scanned into.
+var ErrUnsupportedDestination = errors.New("unsupported destination")
+
+// ErrInvalidStruct is wrapped by errors describing a struct that cannot be
+// scanned into, such as one where two fields map to the same column.
+var ErrInvalidStruct = errors.New("invalid struct")
+
+// ErrTooManyRows is returned by SelectOne when a query returns more than one
+// row.
+var ErrTooManyRows = errors.New("too many rows")
+
+// ErrDuplicateKey is returned when two rows scanned into a map destination
+// have the same key.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// ErrNullKey is returned when a row scanned into a map destination has a NULL
+// value for its key field, or when no column is mapped to the key field.
+var ErrNullKey = errors.New("NULL key")
+
+// ErrColumnConflict is wrapped by a MappingError when a query returns more
+// than one of the column names accepted for a field, such as a field's name
+// and one of its aliases.
+var ErrColumnConflict = errors.New("more than one column for field")
+
+// ErrNoDestinationField is wrapped by a MappingError when a queried column has
+// no struct field to be stored in.
+var ErrNoDestinationField = errors.New("no destination field")
+
+// MappingError describes a failure to map a column from a query to a field of
+// the destination type. Use errors.As to inspect it, and errors.Is to test the
+// underlying cause.
+type MappingError struct {
+	Column string       // Column name as returned by the query
+	Prefix string       // Prefix in effect when mapping the column
+	Type   reflect.Type // Destination type being scanned into
+	Field  string       // Path of Go field names to the field, if known
+	Row    int          // Zero-based index of the row being scanned
+	Err    error        // Underlying cause
+}
+
+func (e *MappingError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("%v for '%s' into %s in %v (row %d)", e.Err, e.Column, e.Field, e.Type, e.Row)
+	}
+	return fmt.Sprintf("%v for '%s' in %v (row %d)", e.Err, e.Column, e.Type, e.Row)
+}
+
+func (e *MappingError) Unwrap() error {
+	return e.Err
+}
+
+// rowsError wraps an error encountered while iterating over or closing rows
+// with the number of rows that had been scanned.
+func rowsError(scanned int, err error) error {
+	return fmt.Errorf("after scanning %d rows: %w", scanned, err)
+}
@@ -10,4 +10,61 @@ type field struct {
 	Name    string
 	Type    reflect.Type
 	Indices []int
+
+	// Aliases holds alternative column names accepted for the field, from
+	// `alias=` tag options.
+	Aliases []string
+
+	// Collection is the slice-of-struct field whose elements this field
+	// belongs to, with Indices relative to the element. It is nil for fields
+	// of the root struct.
+	Collection *collection
+
+	// PrimaryKey is set for fields tagged with the `pk` option, which identify
+	// distinct elements when rows are aggregated.
+	PrimaryKey bool
+
+	// Key is set for fields tagged with the `key` option, which provide the
+	// keys for map destinations.
+	Key bool
+
+	// Rest is set for a map field with the `rest` tag option, which collects
+	// the values of all columns that are not otherwise mapped, keyed by column
+	// name. Type is then the map's value type.
+	Rest bool
+
+	// PrefixMap is set for a map field with the `prefixmap` tag option, which
+	// collects the values of all columns beginning with the field's name and
+	// separator, keyed by the rest of the column name. Type is then the map's
+	// value type.
+	PrefixMap bool
+
+	// ScanType, if set, is the type scanned from the column in place of Type,
+	// for fields whose values are decoded by Decode, such as those with the
+	// `json` tag option.
+	ScanType reflect.Type
+
+	// Decode converts a non-NULL value of ScanType to a value of Type.
+	Decode decodeFunc
+
+	// Depth is the number of embedded structs the field was promoted through.
+	Depth int
+}
+
+// scanType returns the type of value scanned from the column for the field.
+func (f *field) scanType() reflect.Type {
+	if f.ScanType != nil {
+		return f.ScanType
+	}
+	return f.Type
+}
+
+// collection describes a slice-of-struct field, such as the children in a
+// one-to-many join, whose elements are aggregated from successive rows.
+type collection struct {
+	Name     string
+	Type     reflect.Type // Type of the slice
+	Indices  []int        // Path to the slice field within the parent
+	Parent   *collection  // Parent collection, or nil for the root struct
+	Children []*collection
 }
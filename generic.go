@@ -0,0 +1,77 @@
+package structscanner
+
+import (
+	"context"
+	"reflect"
+)
+
+// Query performs a database query and returns all rows scanned into values of
+// type T, which must be a struct, a scalar or a pointer to either, as with a
+// slice destination passed to Select. If no rows are returned by the query, an
+// empty slice is returned.
+func Query[T any](ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) ([]T, error) {
+	return QueryWith[T](defaultMapper, ctx, tx, prefix, query, args...)
+}
+
+// QueryWith is like Query, but uses the configuration of m.
+func QueryWith[T any](m *Mapper, ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) ([]T, error) {
+	var result []T
+
+	err := m.SelectContext(ctx, tx, &result, prefix, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// One performs a database query and returns its single row scanned into a
+// value of type T. If no rows are returned by the query, sql.ErrNoRows is
+// returned, and if more than one row is returned, ErrTooManyRows is returned.
+func One[T any](ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) (T, error) {
+	return OneWith[T](defaultMapper, ctx, tx, prefix, query, args...)
+}
+
+// OneWith is like One, but uses the configuration of m.
+func OneWith[T any](m *Mapper, ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) (T, error) {
+	var result T
+
+	err := m.SelectOneContext(ctx, tx, &result, prefix, query, args...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// First performs a database query and returns its first row scanned into a
+// value of type T. If no rows are returned by the query, sql.ErrNoRows is
+// returned.
+func First[T any](ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) (T, error) {
+	return FirstWith[T](defaultMapper, ctx, tx, prefix, query, args...)
+}
+
+// FirstWith is like First, but uses the configuration of m.
+func FirstWith[T any](m *Mapper, ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) (T, error) {
+	var result T
+
+	err := m.SelectContext(ctx, tx, &result, prefix, query, args...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// ForType is like For, but returns a StructScanner for values of type T
+// without needing a pointer to be passed.
+func ForType[T any](prefix string, opts ...Option) StructScanner {
+	return ForTypeWith[T](defaultMapper, prefix, opts...)
+}
+
+// ForTypeWith is like ForType, but uses the configuration of m.
+func ForTypeWith[T any](m *Mapper, prefix string, opts ...Option) StructScanner {
+	return m.forConfig(reflect.TypeOf((*T)(nil)).Elem(), m.config(prefix, opts))
+}
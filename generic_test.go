@@ -0,0 +1,253 @@
+package structscanner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGeneric(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	type Fruit struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ctx := context.Background()
+
+	t.Run("Query", func(t *testing.T) {
+
+		t.Run("returns slice of results", func(t *testing.T) {
+
+			query := `SELECT f.id, f.name FROM fruits f`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"f.id", "f.name"}).
+						AddRow(1, "apple").
+						AddRow(2, "banana"),
+				)
+
+			result, err := Query[Fruit](ctx, db, "f", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := (Fruit{ID: 2, Name: "banana"}), result[1]; expected != actual {
+				t.Errorf("Expected result 1 to be %v but got %v", expected, actual)
+			}
+		})
+
+		t.Run("returns slice of scalars", func(t *testing.T) {
+
+			query := `SELECT id FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id"}).
+						AddRow(1).
+						AddRow(2),
+				)
+
+			result, err := Query[int64](ctx, db, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := int64(2), result[1]; expected != actual {
+				t.Errorf("Expected result 1 to be %d but got %d", expected, actual)
+			}
+		})
+
+		t.Run("returns slice of values converted with the default Mapper", func(t *testing.T) {
+
+			type Money struct {
+				Cents int64
+			}
+
+			RegisterConverter(nil, func(src any) (Money, error) {
+				return Money{Cents: src.(int64)}, nil
+			})
+
+			query := `SELECT price FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"price"}).
+						AddRow(int64(125)).
+						AddRow(nil),
+				)
+
+			result, err := Query[Money](ctx, db, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := (Money{125}), result[0]; expected != actual {
+				t.Errorf("Expected result 0 to be %v but got %v", expected, actual)
+			}
+			if expected, actual := (Money{}), result[1]; expected != actual {
+				t.Errorf("Expected NULL result to be %v but got %v", expected, actual)
+			}
+		})
+		t.Run("uses the configuration of the Mapper with QueryWith", func(t *testing.T) {
+
+			m := &Mapper{Separator: "__", Normalise: FoldCase}
+
+			query := `SELECT f.id AS F__ID, f.name AS F__NAME FROM fruits f`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"F__ID", "F__NAME"}).
+						AddRow(1, "apple"),
+				)
+
+			result, err := QueryWith[Fruit](m, ctx, db, "f", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 1, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "apple", result[0].Name; expected != actual {
+				t.Errorf("Expected result 0 to have name '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+
+	t.Run("One", func(t *testing.T) {
+
+		t.Run("uses the configuration of the Mapper with OneWith", func(t *testing.T) {
+
+			m := &Mapper{TagName: "sql"}
+
+			type Vegetable struct {
+				Name string `sql:"name"`
+			}
+
+			query := `SELECT name FROM vegetables`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"name"}).
+						AddRow("carrot"),
+				)
+
+			result, err := OneWith[Vegetable](m, ctx, db, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "carrot", result.Name; expected != actual {
+				t.Errorf("Expected name '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("returns single result", func(t *testing.T) {
+
+			query := `SELECT id, name FROM fruits WHERE id = ?`
+
+			dbMock.
+				ExpectQuery(query).
+				WithArgs(1).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(1, "apple"),
+				)
+
+			result, err := One[*Fruit](ctx, db, "", query, 1)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := (Fruit{ID: 1, Name: "apple"}), result; actual == nil || expected != *actual {
+				t.Errorf("Expected result to be %v but got %v", expected, actual)
+			}
+		})
+
+		t.Run("returns ErrTooManyRows when there is more than one row", func(t *testing.T) {
+
+			query := `SELECT id, name FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(1, "apple").
+						AddRow(2, "banana"),
+				)
+
+			_, err := One[Fruit](ctx, db, "", query)
+
+			if !errors.Is(err, ErrTooManyRows) {
+				t.Errorf("Expected ErrTooManyRows but got: %v", err)
+			}
+		})
+	})
+
+	t.Run("First", func(t *testing.T) {
+
+		t.Run("returns first result", func(t *testing.T) {
+
+			query := `SELECT id, name FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(1, "apple").
+						AddRow(2, "banana"),
+				)
+
+			result, err := First[Fruit](ctx, db, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := (Fruit{ID: 1, Name: "apple"}), result; expected != actual {
+				t.Errorf("Expected result to be %v but got %v", expected, actual)
+			}
+		})
+
+		t.Run("returns ErrNoRows when there are no results", func(t *testing.T) {
+
+			query := `SELECT id, name FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}),
+				)
+
+			_, err := First[Fruit](ctx, db, "", query)
+
+			if !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("Expected ErrNoRows but got: %v", err)
+			}
+		})
+	})
+}
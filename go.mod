@@ -1,5 +1,5 @@
 module github.com/folktale-io/structscanner
 
-go 1.18
+go 1.23
 
 require github.com/DATA-DOG/go-sqlmock v1.5.0
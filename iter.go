@@ -0,0 +1,83 @@
+package structscanner
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// Rows performs a database query and returns an iterator over its rows, each
+// scanned into a value of type T, which must be a struct, a scalar or a pointer
+// to either. Rows are scanned one at a time as the iterator is advanced, so
+// large or unbounded results can be processed without holding them all in
+// memory.
+//
+// As rows are scanned one at a time, T must not have collections (slice of
+// struct fields filled from one-to-many joins); if it does, an error wrapping
+// ErrUnsupportedDestination is yielded.
+//
+// Columns are mapped once and reused for every row. The underlying rows are
+// closed when iteration finishes or the loop is exited early. If the query,
+// scanning or iteration fails, the error is yielded with the zero value of T
+// and iteration stops.
+func Rows[T any](ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) iter.Seq2[T, error] {
+	return RowsWith[T](defaultMapper, ctx, tx, prefix, query, args...)
+}
+
+// RowsWith is like Rows, but uses the configuration of m.
+func RowsWith[T any](m *Mapper, ctx context.Context, tx QueryerContext, prefix string, query string, args ...interface{}) iter.Seq2[T, error] {
+	opts, args := splitOptions(args)
+	c := m.config(prefix, opts)
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		elemType := reflect.TypeOf((*T)(nil)).Elem()
+		if !isRowType(elemType) {
+			yield(zero, fmt.Errorf("%w: element must be struct or scalar, got %v", ErrUnsupportedDestination, elemType))
+			return
+		}
+
+		s := m.forConfig(elemType, c)
+		if s.layout.err != nil {
+			yield(zero, s.layout.err)
+			return
+		}
+
+		if len(s.layout.collections) > 0 {
+			yield(zero, fmt.Errorf("%w: element with collections cannot be scanned one row at a time, got %v", ErrUnsupportedDestination, elemType))
+			return
+		}
+
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		defer rows.Close()
+
+		scanned := 0
+
+		for (c.maxRows <= 0 || scanned < c.maxRows) && rows.Next() {
+			var elem T
+
+			err := s.ScanContext(ctx, rows, &elem)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			scanned++
+
+			if !yield(elem, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, rowsError(scanned, err))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package structscanner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRows(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	type Fruit struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	ctx := context.Background()
+
+	t.Run("yields each row", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "apple").
+					AddRow(2, "banana"),
+			).
+			RowsWillBeClosed()
+
+		var result []Fruit
+
+		for fruit, err := range Rows[Fruit](ctx, db, "", query) {
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			result = append(result, fruit)
+		}
+
+		if expected, actual := 2, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+		if expected, actual := (Fruit{ID: 2, Name: "banana"}), result[1]; expected != actual {
+			t.Errorf("Expected result 1 to be %v but got %v", expected, actual)
+		}
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("closes rows when the loop exits early", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "apple").
+					AddRow(2, "banana"),
+			).
+			RowsWillBeClosed()
+
+		count := 0
+
+		for _, err := range Rows[Fruit](ctx, db, "", query) {
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			count++
+			break
+		}
+
+		if expected, actual := 1, count; expected != actual {
+			t.Errorf("Expected %d iterations but got %d", expected, actual)
+		}
+
+		if err := dbMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Expectations were not met: %v", err)
+		}
+	})
+
+	t.Run("yields iteration error last", func(t *testing.T) {
+
+		query := `SELECT id, name FROM fruits`
+		rowErr := errors.New("connection reset")
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "apple").
+					AddRow(2, "banana").
+					RowError(1, rowErr),
+			)
+
+		var errs []error
+
+		for _, err := range Rows[Fruit](ctx, db, "", query) {
+			errs = append(errs, err)
+		}
+
+		if expected, actual := 2, len(errs); expected != actual {
+			t.Fatalf("Expected %d iterations but got %d", expected, actual)
+		}
+		if errs[0] != nil {
+			t.Errorf("Expected first row to succeed but got error: %v", errs[0])
+		}
+		if !errors.Is(errs[1], rowErr) {
+			t.Errorf("Expected row error but got: %v", errs[1])
+		}
+	})
+
+	t.Run("yields ErrUnsupportedDestination for elements with collections", func(t *testing.T) {
+
+		type Org struct {
+			ID     int64   `db:"id,pk"`
+			Fruits []Fruit `db:"f"`
+		}
+
+		var errs []error
+
+		for _, err := range Rows[Org](ctx, db, "", `SELECT o.id, f.id, f.name FROM orgs o JOIN fruits f`) {
+			errs = append(errs, err)
+		}
+
+		if expected, actual := 1, len(errs); expected != actual {
+			t.Fatalf("Expected %d iterations but got %d", expected, actual)
+		}
+		if !errors.Is(errs[0], ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", errs[0])
+		}
+	})
+
+	t.Run("uses the configuration of the Mapper with RowsWith", func(t *testing.T) {
+
+		m := &Mapper{Separator: "__"}
+
+		query := `SELECT id AS f__id, name AS f__name FROM fruits`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"f__id", "f__name"}).
+					AddRow(1, "apple").
+					AddRow(2, "banana"),
+			)
+
+		var names []string
+
+		for fruit, err := range RowsWith[Fruit](m, ctx, db, "f", query) {
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+			names = append(names, fruit.Name)
+		}
+
+		if expected, actual := 2, len(names); expected != actual {
+			t.Fatalf("Expected %d rows but got %d", expected, actual)
+		}
+		if expected, actual := "banana", names[1]; expected != actual {
+			t.Errorf("Expected row 1 to have name '%s' but got '%s'", expected, actual)
+		}
+	})
+}
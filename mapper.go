@@ -0,0 +1,151 @@
+package structscanner
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+const (
+	defaultTagName   = "db"
+	defaultSeparator = "."
+)
+
+var defaultMapper = &Mapper{}
+
+// Mapper holds the configuration used to map database columns to struct
+// fields, along with its own cache of struct layouts. The package-level
+// functions use a default Mapper.
+//
+// The zero value is ready to use with the default configuration. A Mapper is
+// safe for concurrent use by multiple Goroutines, but its fields must not be
+// modified after it is first used.
+type Mapper struct {
+	// TagName is the struct tag used to find column names for fields. If
+	// empty, `db:` tags are used.
+	TagName string
+
+	// IgnoreUnknownColumns sets whether queried columns that have no mapped
+	// struct field are silently ignored, rather than causing a MappingError to
+	// be returned.
+	IgnoreUnknownColumns bool
+
+	// Separator is used to join prefixes and nested struct paths in column
+	// names, such as "__" to map a__b__c to nested structs in the same way as
+	// a.b.c. If empty, a dot (.) is used. It may be overridden for a single
+	// call with WithSeparator.
+	Separator string
+
+	// NameMapper, if set, maps untagged exported fields to columns using the
+	// name it returns for the field's Go name, such as SnakeCase. Fields with
+	// a tag use the name in the tag, and fields tagged with "-" are never
+	// mapped.
+	NameMapper NameMapper
+
+	// Normalise, if set, is applied to column names from the database, to
+	// prefixes and to the column names of struct fields before they are
+	// matched, such as FoldCase for case-insensitive matching. If two fields
+	// of a struct have the same normalised column name, the struct is
+	// invalid.
+	Normalise Normaliser
+
+	// converters holds the converters added with RegisterConverter, by the
+	// type they convert to.
+	converters converters
+
+	layouts sync.Map
+}
+
+func (m *Mapper) tagName() string {
+	if m.TagName == "" {
+		return defaultTagName
+	}
+	return m.TagName
+}
+
+func (m *Mapper) separator() string {
+	if m.Separator == "" {
+		return defaultSeparator
+	}
+	return m.Separator
+}
+
+// fieldName returns the column name for a field that is untagged, or whose tag
+// has no name.
+func (m *Mapper) fieldName(goName string) string {
+	if m.NameMapper == nil {
+		return goName
+	}
+	return m.NameMapper(goName)
+}
+
+func (m *Mapper) normalise(column string) string {
+	if m.Normalise == nil {
+		return column
+	}
+	return m.Normalise(column)
+}
+
+// layoutKey identifies a cached struct layout. Layouts depend on the separator
+// as well as the type, as it is used to join the column names of nested
+// structs.
+type layoutKey struct {
+	structType reflect.Type
+	separator  string
+}
+
+func (m *Mapper) layout(t reflect.Type, separator string) *structLayout {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	key := layoutKey{t, separator}
+
+	cached, ok := m.layouts.Load(key)
+	if !ok {
+		cached, _ = m.layouts.LoadOrStore(key, newStructLayout(t, m, separator))
+	}
+
+	return cached.(*structLayout)
+}
+
+// For returns a StructScanner suitable for scanning a struct of the type
+// given by structPtr, using the configuration of the Mapper.
+//
+// A prefix may be specified; struct fields are mapped assuming that the columns
+// from the database have the specified prefix followed by the Mapper's
+// separator. Options may be given to override the configuration of the Mapper
+// for this StructScanner.
+func (m *Mapper) For(structPtr interface{}, prefix string, opts ...Option) StructScanner {
+	return m.forConfig(reflect.TypeOf(structPtr), m.config(prefix, opts))
+}
+
+func (m *Mapper) forConfig(t reflect.Type, c config) StructScanner {
+	scanner := StructScanner{
+		mapper: m,
+		config: c,
+		layout: m.layout(t, c.separator),
+	}
+	return scanner
+}
+
+// Validate checks that the struct pointed to by structPtr can be scanned into
+// using the configuration of the Mapper, returning an error wrapping
+// ErrInvalidStruct that describes any problems found. These include tagged
+// fields that are unexported or of unsupported types, such as channels and
+// functions, and fields that map to the same column.
+//
+// Problems are otherwise only reported when a query is scanned, so Validate is
+// useful to call from tests or init functions.
+func (m *Mapper) Validate(structPtr interface{}) error {
+	t := reflect.TypeOf(structPtr)
+	if t == nil {
+		return fmt.Errorf("%w: struct expected, got nil", ErrUnsupportedDestination)
+	}
+
+	if !isRowType(t) {
+		return fmt.Errorf("%w: struct expected, got %v", ErrUnsupportedDestination, t)
+	}
+
+	return m.layout(t, m.separator()).err
+}
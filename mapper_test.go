@@ -0,0 +1,424 @@
+package structscanner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMapper(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("maps fields using the configured tag name and separator", func(t *testing.T) {
+
+		m := &Mapper{
+			TagName:   "sql",
+			Separator: "__",
+		}
+
+		var result struct {
+			Value  string `sql:"value"`
+			Nested struct {
+				Value string `sql:"value"`
+			} `sql:"nested"`
+		}
+
+		query := `SELECT value, nested_value`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"p__value", "p__nested__value"}).
+					AddRow("value", "nested value"),
+			)
+
+		err := m.Select(db, &result, "p", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := "value", result.Value; expected != actual {
+			t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+		}
+		if expected, actual := "nested value", result.Nested.Value; expected != actual {
+			t.Errorf("Expected nested value '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("ignores unknown columns without affecting the default mapper", func(t *testing.T) {
+
+		m := &Mapper{
+			IgnoreUnknownColumns: true,
+		}
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value, other`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value", "other"}).
+					AddRow("value", "other"),
+			)
+
+		err := m.Select(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value", "other"}).
+					AddRow("value", "other"),
+			)
+
+		err = Select(db, &result, "", query)
+		if !errors.Is(err, ErrNoDestinationField) {
+			t.Errorf("Expected ErrNoDestinationField from default mapper but got: %v", err)
+		}
+	})
+
+	t.Run("normalises column names before matching", func(t *testing.T) {
+
+		m := &Mapper{
+			Normalise: strings.ToLower,
+		}
+
+		var result []struct {
+			Value string `db:"Value"`
+		}
+
+		query := `SELECT VALUE`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"VALUE"}).
+					AddRow("value"),
+			)
+
+		err := m.Select(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 1, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+		if expected, actual := "value", result[0].Value; expected != actual {
+			t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("normalises prefixes and quoted column names", func(t *testing.T) {
+
+		m := &Mapper{
+			Normalise: Normalisers(StripQuotes, FoldCase),
+		}
+
+		var result struct {
+			UserID int64 `db:"userId"`
+		}
+
+		query := `SELECT "U"."USERID"`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{`"U"."USERID"`}).
+					AddRow(42),
+			)
+
+		err := m.Select(db, &result, "u", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := int64(42), result.UserID; expected != actual {
+			t.Errorf("Expected %d but got %d", expected, actual)
+		}
+	})
+
+	t.Run("reports fields that are ambiguous once normalised", func(t *testing.T) {
+
+		m := &Mapper{
+			Normalise: FoldCase,
+		}
+
+		type TestStruct struct {
+			UserID int64 `db:"userId"`
+			UserId int64 `db:"userid"`
+		}
+
+		err := m.Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Fatalf("Expected ErrInvalidStruct but got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "ambiguous") {
+			t.Errorf("Expected error to describe ambiguity but got: %v", err)
+		}
+	})
+
+	t.Run("scans rows that have already been queried", func(t *testing.T) {
+
+		m := &Mapper{}
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("value 1").
+					AddRow("value 2"),
+			)
+
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("Error executing query: %v", err)
+		}
+		defer rows.Close()
+
+		err = m.Scan(rows, &result, "")
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 2, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+	})
+
+	t.Run("converts values with registered converters", func(t *testing.T) {
+
+		type Money struct {
+			Cents int64
+		}
+
+		m := &Mapper{}
+
+		RegisterConverter(m, func(src any) (bool, error) {
+			switch string(src.([]byte)) {
+			case "Y":
+				return true, nil
+			case "N":
+				return false, nil
+			}
+			return false, errors.New("expected Y or N")
+		})
+		RegisterConverter(m, func(src any) (Money, error) {
+			return Money{Cents: src.(int64)}, nil
+		})
+
+		var result []struct {
+			Active bool   `db:"active"`
+			Price  Money  `db:"price"`
+			Tax    *Money `db:"tax"`
+		}
+
+		query := `SELECT active, price, tax FROM items`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"active", "price", "tax"}).
+					AddRow([]byte("Y"), int64(1250), int64(250)).
+					AddRow([]byte("N"), nil, nil),
+			)
+
+		err := m.Select(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 2, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+		if !result[0].Active || result[1].Active {
+			t.Errorf("Expected active to be converted but got %v and %v", result[0].Active, result[1].Active)
+		}
+		if expected, actual := (Money{1250}), result[0].Price; expected != actual {
+			t.Errorf("Expected price %v but got %v", expected, actual)
+		}
+		if result[0].Tax == nil || result[0].Tax.Cents != 250 {
+			t.Errorf("Expected tax of 250 cents but got %v", result[0].Tax)
+		}
+		if result[1].Price != (Money{}) || result[1].Tax != nil {
+			t.Errorf("Expected NULLs to give zero values but got %+v", result[1])
+		}
+	})
+
+	t.Run("converts scalar destinations with registered converters", func(t *testing.T) {
+
+		type YN bool
+
+		m := &Mapper{}
+
+		RegisterConverter(m, func(src any) (YN, error) {
+			return YN(string(src.([]byte)) == "Y"), nil
+		})
+
+		query := `SELECT active FROM items`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"active"}).
+					AddRow([]byte("Y")),
+			)
+
+		var single YN
+
+		err := m.Select(db, &single, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if !single {
+			t.Errorf("Expected single value to be converted to true")
+		}
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"active"}).
+					AddRow([]byte("N")).
+					AddRow([]byte("Y")).
+					AddRow(nil),
+			)
+
+		var result []*YN
+
+		err = m.Select(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 3, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+		if result[0] == nil || *result[0] || result[1] == nil || !*result[1] || result[2] != nil {
+			t.Errorf("Expected [false true nil] but got %v", result)
+		}
+	})
+
+	t.Run("converts prefix map values and elements with registered converters", func(t *testing.T) {
+
+		type Grade int
+
+		m := &Mapper{}
+
+		RegisterConverter(m, func(src any) (Grade, error) {
+			s, _ := src.(string)
+			if len(s) != 1 || s[0] < 'A' || s[0] > 'F' {
+				return 0, fmt.Errorf("invalid grade %q", s)
+			}
+			return Grade(s[0] - 'A' + 1), nil
+		})
+
+		type Result struct {
+			Subject string `db:"subject"`
+			Grade   Grade  `db:"grade"`
+		}
+
+		var result struct {
+			ByTerm map[string]Grade `db:"term,prefixmap"`
+			Split  []Grade          `db:"split,split=|"`
+			Array  []Grade          `db:"array"`
+			Best   Result           `db:"best,composite"`
+		}
+
+		query := `SELECT "term.1", "term.2", "term.3", split, array, best FROM reports`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"term.1", "term.2", "term.3", "split", "array", "best"}).
+					AddRow("A", "C", nil, "B|D", "{E,F}", "(maths,A)"),
+			)
+
+		err := m.SelectOne(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := fmt.Sprint(map[string]Grade{"1": 1, "2": 3}), fmt.Sprint(result.ByTerm); expected != actual {
+			t.Errorf("Expected prefix map %s but got %s", expected, actual)
+		}
+		if expected, actual := "[2 4]", fmt.Sprint(result.Split); expected != actual {
+			t.Errorf("Expected split elements %s but got %s", expected, actual)
+		}
+		if expected, actual := "[5 6]", fmt.Sprint(result.Array); expected != actual {
+			t.Errorf("Expected array elements %s but got %s", expected, actual)
+		}
+		if expected, actual := (Result{Subject: "maths", Grade: 1}), result.Best; expected != actual {
+			t.Errorf("Expected composite %v but got %v", expected, actual)
+		}
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"term.1", "term.2", "term.3", "split", "array", "best"}).
+					AddRow("A", "C", nil, "B|D", "{E,Z}", "(maths,A)"),
+			)
+
+		err = m.SelectOne(db, &result, "", query)
+
+		var mappingErr *MappingError
+		if !errors.As(err, &mappingErr) {
+			t.Fatalf("Expected MappingError but got: %v", err)
+		}
+		if expected, actual := "Array", mappingErr.Field; expected != actual {
+			t.Errorf("Expected error for field '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("returns MappingError for converter errors", func(t *testing.T) {
+
+		m := &Mapper{}
+
+		RegisterConverter(m, func(src any) (bool, error) {
+			return false, errors.New("expected Y or N")
+		})
+
+		var result struct {
+			Active bool `db:"active"`
+		}
+
+		query := `SELECT active FROM items`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"active"}).
+					AddRow("maybe"),
+			)
+
+		err := m.SelectOne(db, &result, "", query)
+
+		var mappingErr *MappingError
+		if !errors.As(err, &mappingErr) {
+			t.Fatalf("Expected MappingError but got: %v", err)
+		}
+		if expected, actual := "Active", mappingErr.Field; expected != actual {
+			t.Errorf("Expected field '%s' but got '%s'", expected, actual)
+		}
+	})
+}
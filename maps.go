@@ -0,0 +1,218 @@
+package structscanner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isMapDestination reports whether t is a map type that rows can be scanned
+// into: a map of structs, pointers to structs, or slices of either.
+func isMapDestination(t reflect.Type) bool {
+	if t.Kind() != reflect.Map {
+		return false
+	}
+
+	elemType := mapElemType(t)
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	return elemType.Kind() == reflect.Struct && !isScalar(elemType)
+}
+
+// mapElemType returns the type of the values scanned from each row for a map
+// destination of type t. This is the map's value type, or its element type if
+// the map groups rows into slices.
+func mapElemType(t reflect.Type) reflect.Type {
+	valueType := t.Elem()
+	if valueType.Kind() == reflect.Slice {
+		return valueType.Elem()
+	}
+	return valueType
+}
+
+// scanMap scans rows into destPtr, a pointer to a map, keyed by the value of
+// the key field of each element. The map type must already have been checked
+// with checkLayout.
+func (m *Mapper) scanMap(ctx context.Context, rows *sql.Rows, destPtr interface{}, c config) (int, error) {
+	mapType := reflect.TypeOf(destPtr).Elem()
+	elemType := mapElemType(mapType)
+	grouped := mapType.Elem().Kind() == reflect.Slice
+
+	keyField, err := m.layout(elemType, c.separator).mapKeyField(m, c.key)
+	if err != nil {
+		return 0, err
+	}
+
+	resultPtr := reflect.New(reflect.SliceOf(elemType))
+
+	c.keyField = keyField
+
+	scanned, err := m.scanRows(ctx, rows, resultPtr.Interface(), c)
+	if err != nil {
+		return scanned, err
+	}
+
+	result := resultPtr.Elem()
+	mapValue := reflect.MakeMapWithSize(mapType, result.Len())
+
+	for i := 0; i < result.Len(); i++ {
+		elem := result.Index(i)
+
+		// Rows whose columns are all NULL leave nil pointer elements, which
+		// are skipped as for slices of structs with collections
+		if isNilPtr(elem) {
+			continue
+		}
+
+		key, err := mapKey(elem, keyField, mapType.Key())
+		if err != nil {
+			return scanned, err
+		}
+
+		if grouped {
+			group := mapValue.MapIndex(key)
+			if !group.IsValid() {
+				group = reflect.Zero(mapType.Elem())
+			}
+			mapValue.SetMapIndex(key, reflect.Append(group, elem))
+
+		} else {
+			if mapValue.MapIndex(key).IsValid() {
+				return scanned, fmt.Errorf("%w: %v", ErrDuplicateKey, key)
+			}
+			mapValue.SetMapIndex(key, elem)
+		}
+	}
+
+	reflect.ValueOf(destPtr).Elem().Set(mapValue)
+
+	return scanned, nil
+}
+
+// mapKeyField finds the root-level field used as the key for a map
+// destination. If name is given, it is matched against both the column names
+// and the Go field names of fields; otherwise the field tagged with the `key`
+// option is used, or failing that, a single field tagged with `pk`.
+func (sl *structLayout) mapKeyField(m *Mapper, name string) (*field, error) {
+	var keyFields, pkFields []*field
+
+	for i := range sl.fields {
+		f := &sl.fields[i]
+		if f.Collection != nil {
+			continue
+		}
+
+		if name != "" {
+			if m.normalise(f.Name) == m.normalise(name) || sl.goFieldName(f) == name {
+				return f, nil
+			}
+			continue
+		}
+
+		if f.Key {
+			keyFields = append(keyFields, f)
+		}
+		if f.PrimaryKey {
+			pkFields = append(pkFields, f)
+		}
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("%w: no key field '%s' in %v", ErrUnsupportedDestination, name, sl.structType)
+	}
+
+	if len(keyFields) == 1 {
+		return keyFields[0], nil
+	}
+	if len(keyFields) == 0 && len(pkFields) == 1 {
+		return pkFields[0], nil
+	}
+
+	return nil, fmt.Errorf("%w: map destination needs a single key field in %v", ErrUnsupportedDestination, sl.structType)
+}
+
+// goFieldName returns the dotted path of Go field names leading to f, from the
+// root struct through any collections it belongs to.
+func (sl *structLayout) goFieldName(f *field) string {
+	if f.Collection == nil {
+		return goFieldPath(sl.structType, f.Indices)
+	}
+
+	c := f.Collection
+	parent := &field{Indices: c.Indices, Collection: c.Parent}
+
+	return sl.goFieldName(parent) + "." + goFieldPath(c.Type.Elem(), f.Indices)
+}
+
+// goFieldPath returns the dotted path of Go field names at indices within t.
+func goFieldPath(t reflect.Type, indices []int) string {
+	names := make([]string, len(indices))
+
+	for i, index := range indices {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		sf := t.Field(index)
+		names[i] = sf.Name
+		t = sf.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// isKeyConvertible reports whether values of type t can be used as map keys of
+// type keyType, either directly or by converting between types of the same
+// kind, such as int32 to int64. Conversions that change the meaning of a
+// value, such as from an integer to a string, are not allowed.
+func isKeyConvertible(t reflect.Type, keyType reflect.Type) bool {
+	if t.AssignableTo(keyType) {
+		return true
+	}
+
+	return t.ConvertibleTo(keyType) && kindFamily(t.Kind()) == kindFamily(keyType.Kind())
+}
+
+// kindFamily groups the sized variants of integer, unsigned integer, float and
+// complex kinds together.
+func kindFamily(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	case reflect.Complex64, reflect.Complex128:
+		return reflect.Complex128
+	}
+	return k
+}
+
+// mapKey returns the value of keyField in elem, converted to keyType, which
+// must already have been checked with isKeyConvertible. NULL keys are detected
+// while scanning, but an error wrapping ErrNullKey is also returned here if
+// the key is a nil pointer.
+func mapKey(elem reflect.Value, keyField *field, keyType reflect.Type) (reflect.Value, error) {
+	v, ok := valueByIndices(indirect(elem), keyField.Indices)
+	if ok && v.Kind() == reflect.Ptr {
+		ok = !v.IsNil()
+		if ok {
+			v = v.Elem()
+		}
+	}
+
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%w: key field '%s' is NULL", ErrNullKey, keyField.Name)
+	}
+
+	if v.Type().AssignableTo(keyType) {
+		return v, nil
+	}
+
+	return v.Convert(keyType), nil
+}
@@ -0,0 +1,58 @@
+package structscanner
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NameMapper maps the Go name of an untagged struct field to a column name.
+// See Mapper.NameMapper.
+type NameMapper func(fieldName string) string
+
+// SnakeCase maps field names to snake_case column names, so that CreatedAt
+// maps to created_at, and UserID to user_id.
+func SnakeCase(fieldName string) string {
+	runes := []rune(fieldName)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// LowerCamelCase maps field names to lowerCamelCase column names, so that
+// CreatedAt maps to createdAt, ID to id and HTTPStatus to httpStatus.
+func LowerCamelCase(fieldName string) string {
+	runes := []rune(fieldName)
+
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+
+		// Keep the last capital of a leading acronym that begins a new word
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(r)
+	}
+
+	return string(runes)
+}
+
+// ExactName maps field names to identical column names.
+func ExactName(fieldName string) string {
+	return fieldName
+}
@@ -0,0 +1,85 @@
+package structscanner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+
+	t.Run("SnakeCase", func(t *testing.T) {
+		for fieldName, expected := range map[string]string{
+			"Name":       "name",
+			"CreatedAt":  "created_at",
+			"ID":         "id",
+			"UserID":     "user_id",
+			"HTTPServer": "http_server",
+			"Address2":   "address2",
+			"Line2Text":  "line2_text",
+		} {
+			if actual := SnakeCase(fieldName); expected != actual {
+				t.Errorf("Expected '%s' to map to '%s' but got '%s'", fieldName, expected, actual)
+			}
+		}
+	})
+
+	t.Run("LowerCamelCase", func(t *testing.T) {
+		for fieldName, expected := range map[string]string{
+			"Name":       "name",
+			"CreatedAt":  "createdAt",
+			"ID":         "id",
+			"UserID":     "userID",
+			"HTTPServer": "httpServer",
+		} {
+			if actual := LowerCamelCase(fieldName); expected != actual {
+				t.Errorf("Expected '%s' to map to '%s' but got '%s'", fieldName, expected, actual)
+			}
+		}
+	})
+
+	t.Run("maps untagged exported fields when configured", func(t *testing.T) {
+
+		type Nested struct {
+			PostCode string
+		}
+
+		type TestStruct struct {
+			UserID    int64
+			FullName  string `db:"name"`
+			Ignored   string `db:"-"`
+			Address   Nested
+			Callback  func()
+			unexposed string
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{NameMapper: SnakeCase}, ".")
+
+		if sl.err != nil {
+			t.Fatalf("Expected valid layout but got error: %v", sl.err)
+		}
+
+		for _, name := range []string{"user_id", "name", "address.post_code"} {
+			if sl.fieldsByName[name] == nil {
+				t.Errorf("Expected field for '%s' but found none", name)
+			}
+		}
+		for _, name := range []string{"full_name", "ignored", "callback", "unexposed"} {
+			if sl.fieldsByName[name] != nil {
+				t.Errorf("Expected no field for '%s' but found one", name)
+			}
+		}
+	})
+
+	t.Run("does not map untagged fields by default", func(t *testing.T) {
+
+		type TestStruct struct {
+			UserID int64
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{}, ".")
+
+		if expected, actual := 0, len(sl.fields); expected != actual {
+			t.Errorf("Expected %d fields but got %d", expected, actual)
+		}
+	})
+}
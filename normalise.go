@@ -0,0 +1,38 @@
+package structscanner
+
+import "strings"
+
+// Normaliser transforms column names before they are matched, so that names
+// differing only in ways that the database does not preserve, such as case,
+// still match. See Mapper.Normalise.
+type Normaliser func(column string) string
+
+// FoldCase normalises column names to lower case, so that matching is case
+// insensitive. This suits databases such as Postgres that fold unquoted names
+// to lower case, and SQL Server, which returns names in whatever case the
+// query used.
+func FoldCase(column string) string {
+	return strings.ToLower(column)
+}
+
+// StripQuotes normalises column names by removing identifier quote
+// characters: double quotes, backticks and square brackets.
+func StripQuotes(column string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '`', '[', ']':
+			return -1
+		}
+		return r
+	}, column)
+}
+
+// Normalisers returns a Normaliser that applies each of normalisers in turn.
+func Normalisers(normalisers ...Normaliser) Normaliser {
+	return func(column string) string {
+		for _, n := range normalisers {
+			column = n(column)
+		}
+		return column
+	}
+}
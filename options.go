@@ -1,10 +1,126 @@
 package structscanner
 
-var ignoreNonexistentFields = false
-
 // IgnoreNonexistentFields sets whether missing struct fields (queried columns
-// that have no mapped struct field to be stored in) trigger a panic, or are
-// silently ignored.
+// that have no mapped struct field to be stored in) cause a MappingError to be
+// returned, or are silently ignored, when using the package-level functions.
+//
+// Deprecated: IgnoreNonexistentFields changes the configuration of the default
+// Mapper for the whole program and is not safe to call concurrently with
+// queries. Use a Mapper with IgnoreUnknownColumns set, or pass
+// WithIgnoreUnknownColumns to individual calls, instead.
 func IgnoreNonexistentFields(ignore bool) {
-	ignoreNonexistentFields = ignore
+	defaultMapper.IgnoreUnknownColumns = ignore
+}
+
+// Option overrides the configuration of a Mapper for a single call.
+//
+// Options are passed to For as trailing arguments. For Select and its
+// variants, options may be given amongst the query arguments; they are removed
+// before the query is performed.
+type Option func(*config)
+
+// config is the configuration in effect for a single call.
+type config struct {
+	prefix               string
+	separator            string
+	ignoreUnknownColumns bool
+	maxRows              int
+	singleRow            bool
+	key                  string
+
+	// keyField, if set, is the key field for a map destination, which must
+	// not be NULL in any row scanned.
+	keyField *field
+}
+
+// WithIgnoreUnknownColumns causes queried columns that have no mapped struct
+// field to be silently ignored.
+func WithIgnoreUnknownColumns() Option {
+	return func(c *config) {
+		c.ignoreUnknownColumns = true
+	}
+}
+
+// WithMaxRows limits the number of rows scanned into a slice destination to n.
+// Any further rows are left unread.
+//
+// As the rows for an element with collections may be spread throughout the
+// results, WithMaxRows cannot be used with such elements, and an error
+// wrapping ErrUnsupportedDestination is returned.
+func WithMaxRows(n int) Option {
+	return func(c *config) {
+		c.maxRows = n
+	}
+}
+
+// WithPrefix sets the prefix that column names are assumed to begin with,
+// overriding the prefix given as an argument.
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithSeparator sets the separator used to join prefixes and nested struct
+// paths in column names, overriding the separator of the Mapper. An empty
+// separator selects the default dot (.) separator.
+func WithSeparator(separator string) Option {
+	return func(c *config) {
+		if separator == "" {
+			separator = defaultSeparator
+		}
+		c.separator = separator
+	}
+}
+
+// WithKey sets the field used as the key when scanning into a map
+// destination. name may be either a column name or a Go field name.
+func WithKey(name string) Option {
+	return func(c *config) {
+		c.key = name
+	}
+}
+
+// withSingleRow causes ErrTooManyRows to be returned if a query for a single
+// row returns more than one.
+func withSingleRow() Option {
+	return func(c *config) {
+		c.singleRow = true
+	}
+}
+
+// config returns the configuration of the Mapper with prefix and opts
+// applied.
+func (m *Mapper) config(prefix string, opts []Option) config {
+	c := config{
+		prefix:               prefix,
+		separator:            m.separator(),
+		ignoreUnknownColumns: m.IgnoreUnknownColumns,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// splitOptions separates any options from query arguments.
+func splitOptions(args []interface{}) ([]Option, []interface{}) {
+	var opts []Option
+	var queryArgs []interface{}
+
+	for _, arg := range args {
+		if opt, ok := arg.(Option); ok {
+			opts = append(opts, opt)
+		} else {
+			queryArgs = append(queryArgs, arg)
+		}
+	}
+
+	if opts == nil {
+		return nil, args
+	}
+
+	return opts, queryArgs
 }
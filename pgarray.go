@@ -0,0 +1,189 @@
+package structscanner
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var errInvalidArray = errors.New("invalid array literal")
+
+// isArrayType reports whether t is a slice filled from a Postgres array
+// literal: a slice of elements that can be converted from strings, or a slice
+// of such slices for multidimensional arrays. Byte slices and types
+// implementing sql.Scanner are scanned directly instead. Elements may have a
+// converter in cs.
+func isArrayType(t reflect.Type, cs converters) bool {
+	if t.Kind() != reflect.Slice || isScalar(t) {
+		return false
+	}
+
+	elemType := t.Elem()
+	if elemType.Kind() == reflect.Slice && !isScalar(elemType) {
+		return isArrayType(elemType, cs)
+	}
+
+	return isStringConvertible(elemType, cs)
+}
+
+// decodeArray returns a decodeFunc that parses a Postgres array literal, such
+// as {1,2,3} or {{"a","b"},{NULL,"c"}}, into a value of the slice type t.
+func decodeArray(t reflect.Type, cs converters) decodeFunc {
+	return func(src reflect.Value) (reflect.Value, error) {
+		return parseArray(string(src.Bytes()), t, cs)
+	}
+}
+
+// parseArray parses the Postgres array literal s into a value of the slice
+// type t. NULL elements are set to the zero value of the element type, which
+// is nil for pointers. Elements are converted using the converters in cs.
+func parseArray(s string, t reflect.Type, cs converters) (reflect.Value, error) {
+	p := &arrayParser{s: s, converters: cs}
+
+	// Skip any dimension decoration, such as [0:2]={1,2,3}
+	if strings.HasPrefix(s, "[") {
+		if i := strings.Index(s, "="); i >= 0 {
+			p.pos = i + 1
+		}
+	}
+
+	v, err := p.parseArray(t)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return reflect.Value{}, p.error("unexpected text after array")
+	}
+
+	return v, nil
+}
+
+// arrayParser parses Postgres array literals.
+type arrayParser struct {
+	s          string
+	pos        int
+	converters converters
+}
+
+func (p *arrayParser) error(message string) error {
+	return fmt.Errorf("%w: %s at position %d of %q", errInvalidArray, message, p.pos, p.s)
+}
+
+func (p *arrayParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\n\r\v\f", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// parseArray parses an array, starting at its opening brace, into a value of
+// the slice type t.
+func (p *arrayParser) parseArray(t reflect.Type) (reflect.Value, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != '{' {
+		return reflect.Value{}, p.error("expected '{'")
+	}
+	p.pos++
+
+	v := reflect.MakeSlice(t, 0, 0)
+	elemType := t.Elem()
+
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return v, nil
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return reflect.Value{}, p.error("unterminated array")
+		}
+
+		var elem reflect.Value
+
+		if p.s[p.pos] == '{' {
+			if !isArrayType(elemType, p.converters) {
+				return reflect.Value{}, p.error(fmt.Sprintf("too many dimensions for %v", t))
+			}
+
+			var err error
+			if elem, err = p.parseArray(elemType); err != nil {
+				return reflect.Value{}, err
+			}
+
+		} else {
+			text, quoted, err := p.parseElement()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			if !quoted && strings.EqualFold(text, "NULL") {
+				elem = reflect.Zero(elemType)
+			} else if elem, err = convertString(text, elemType, p.converters); err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", v.Len(), err)
+			}
+		}
+
+		v = reflect.Append(v, elem)
+
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return reflect.Value{}, p.error("unterminated array")
+		}
+
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return v, nil
+		default:
+			return reflect.Value{}, p.error("expected ',' or '}'")
+		}
+	}
+}
+
+// parseElement parses a quoted or unquoted element, returning its text with
+// any escapes removed, and whether it was quoted. Unquoted elements have
+// surrounding whitespace removed.
+func (p *arrayParser) parseElement() (string, bool, error) {
+	var b strings.Builder
+
+	quoted := p.s[p.pos] == '"'
+	if quoted {
+		p.pos++
+	}
+
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+
+		switch {
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.s) {
+				return "", false, p.error("unterminated escape")
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+			continue
+
+		case quoted && c == '"':
+			p.pos++
+			return b.String(), true, nil
+
+		case !quoted && (c == ',' || c == '}'):
+			return strings.TrimSpace(b.String()), false, nil
+
+		case !quoted && (c == '{' || c == '"'):
+			return "", false, p.error(fmt.Sprintf("unexpected '%c'", c))
+		}
+
+		b.WriteByte(c)
+		p.pos++
+	}
+
+	return "", false, p.error("unterminated element")
+}
@@ -0,0 +1,88 @@
+package structscanner
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseArray(t *testing.T) {
+
+	t.Run("parses one-dimensional arrays", func(t *testing.T) {
+		for literal, expected := range map[string][]string{
+			`{}`:                      {},
+			`{a,b,c}`:                 {"a", "b", "c"},
+			`{ a , b }`:               {"a", "b"},
+			`{"a b","c,d","e\"f"}`:    {"a b", "c,d", `e"f`},
+			`{"NULL",null}`:           {"NULL", ""},
+			`{a\,b,"c\\d"}`:           {"a,b", `c\d`},
+			`[1:2]={x,y}`:             {"x", "y"},
+			`{"{braces}","",unicode}`: {"{braces}", "", "unicode"},
+		} {
+			actual, err := parseArray(literal, reflect.TypeOf([]string(nil)), nil)
+			if err != nil {
+				t.Errorf("Expected success for %s but got error: %v", literal, err)
+				continue
+			}
+			if !reflect.DeepEqual(expected, actual.Interface()) {
+				t.Errorf("Expected %s to parse to %q but got %q", literal, expected, actual.Interface())
+			}
+		}
+	})
+
+	t.Run("parses multidimensional arrays", func(t *testing.T) {
+
+		actual, err := parseArray(`{{1,2},{3,NULL}}`, reflect.TypeOf([][]int64(nil)), nil)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected := [][]int64{{1, 2}, {3, 0}}; !reflect.DeepEqual(expected, actual.Interface()) {
+			t.Errorf("Expected %v but got %v", expected, actual.Interface())
+		}
+	})
+
+	t.Run("parses NULL elements into nil pointers", func(t *testing.T) {
+
+		actual, err := parseArray(`{1,NULL}`, reflect.TypeOf([]*int64(nil)), nil)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		elems := actual.Interface().([]*int64)
+		if len(elems) != 2 || elems[0] == nil || *elems[0] != 1 || elems[1] != nil {
+			t.Errorf("Expected [1 nil] but got %v", elems)
+		}
+	})
+
+	t.Run("parses elements with sql.Scanner", func(t *testing.T) {
+
+		actual, err := parseArray(`{a,NULL}`, reflect.TypeOf([]sql.NullString(nil)), nil)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		expected := []sql.NullString{{String: "a", Valid: true}, {}}
+		if !reflect.DeepEqual(expected, actual.Interface()) {
+			t.Errorf("Expected %v but got %v", expected, actual.Interface())
+		}
+	})
+
+	t.Run("returns errors for invalid literals", func(t *testing.T) {
+		for _, literal := range []string{
+			``,
+			`{`,
+			`{a,b`,
+			`{"a}`,
+			`{a}b`,
+			`{{a}}`,
+			`{a"b}`,
+		} {
+			_, err := parseArray(literal, reflect.TypeOf([]string(nil)), nil)
+			if !errors.Is(err, errInvalidArray) {
+				t.Errorf("Expected invalid array error for %s but got: %v", literal, err)
+			}
+		}
+	})
+}
@@ -1,7 +1,28 @@
 package structscanner
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
+// Queryer is implemented by types that can perform a database query, such as
+// *sql.DB and *sql.Tx.
 type Queryer interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 }
+
+// QueryerContext is implemented by types that can perform a database query
+// with a context, such as *sql.DB, *sql.Tx and *sql.Conn.
+type QueryerContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryerWithoutContext adapts a Queryer to QueryerContext by ignoring the
+// context passed to QueryContext.
+type queryerWithoutContext struct {
+	Queryer
+}
+
+func (q queryerWithoutContext) QueryContext(_ context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.Query(query, args...)
+}
@@ -1,13 +1,16 @@
 package structscanner
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 )
 
 // Select performs a database query, then scans the results into destPtr, which
-// must be a pointer to a struct or a slice.
+// must be a non-nil pointer to a struct, a scalar, a slice of either, or a
+// map.
 //
 // If the destination is a struct, a single row is scanned into the struct. If
 // no rows are returned by the query, sql.ErrNoRows is returned.
@@ -16,51 +19,369 @@ import (
 // in the slice. If no rows are returned by the query, the destination will be
 // an empty slice.
 //
+// Struct fields that are slices of structs are filled from one-to-many joins:
+// rows with the same key are merged into a single element, and the distinct
+// elements from each row are appended to the slice field. Elements are keyed
+// on fields with a `pk` tag option, such as `db:"id,pk"`, or on all of their
+// fields if there are none. Elements whose columns are all NULL, as from a LEFT
+// JOIN with no match, are skipped, as are rows whose columns are all NULL when
+// the destination's elements are pointers. Collections may be nested to any
+// depth.
+//
+// With a single struct destination that has such fields, all rows are read and
+// those for the first element are merged into it.
+//
 // Columns returned from the query are mapped to struct fields using their `db:`
 // tags, and column names are assumed to begin with prefix when mapping.
+//
+// If the destination is a map of structs or pointers to structs, each row is
+// stored in the map under the value of its key field, and ErrDuplicateKey is
+// returned if two rows have the same key. If the map's values are slices, rows
+// with the same key are grouped together. The key field is the one tagged with
+// the `key` option, such as `db:"id,key"`, or the one given with WithKey; if
+// there is neither, a single field tagged with `pk` is used. The key field must
+// be of the same kind as the map's keys, such as an integer for a
+// map[uint64]T. ErrNullKey is returned if a key is NULL, or if no column is
+// mapped to the key field. Rows whose
+// columns are all NULL are skipped when the map's values are pointers.
+//
+// Scalar destinations, such as int64, string, time.Time or types implementing
+// sql.Scanner, are scanned directly from the first column of a row, and NULLs
+// are mapped to zero values.
+//
+// Options may be given amongst args to override the default configuration for
+// this call only; they are not passed to the database.
 func Select(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) error {
-	destType := reflect.TypeOf(destPtr)
-	if destType.Kind() != reflect.Ptr {
-		panic("pointer destination expected")
+	return defaultMapper.Select(tx, destPtr, prefix, query, args...)
+}
+
+// SelectContext is like Select, but performs the query using ctx. When the
+// destination is a slice, scanning stops between rows with the context's error
+// once ctx is done.
+//
+// Errors encountered while iterating over or closing the rows are returned
+// wrapped with the number of rows scanned so far.
+func SelectContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	return defaultMapper.SelectContext(ctx, tx, destPtr, prefix, query, args...)
+}
+
+// SelectOne is like Select, but destPtr must not be a slice, and
+// ErrTooManyRows is returned if the query returns more than one row.
+func SelectOne(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	return defaultMapper.SelectOne(tx, destPtr, prefix, query, args...)
+}
+
+// SelectOneContext is like SelectOne, but performs the query using ctx.
+func SelectOneContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	return defaultMapper.SelectOneContext(ctx, tx, destPtr, prefix, query, args...)
+}
+
+// SelectOptional is like Select, but destPtr must not be a slice, and rather
+// than returning sql.ErrNoRows it reports whether a row was found.
+func SelectOptional(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) (bool, error) {
+	return defaultMapper.SelectOptional(tx, destPtr, prefix, query, args...)
+}
+
+// SelectOptionalContext is like SelectOptional, but performs the query using
+// ctx.
+func SelectOptionalContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) (bool, error) {
+	return defaultMapper.SelectOptionalContext(ctx, tx, destPtr, prefix, query, args...)
+}
+
+// Select is like the package-level Select, but uses the configuration of the
+// Mapper.
+func (m *Mapper) Select(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	return m.SelectContext(context.Background(), queryerWithoutContext{tx}, destPtr, prefix, query, args...)
+}
+
+// SelectContext is like the package-level SelectContext, but uses the
+// configuration of the Mapper.
+func (m *Mapper) SelectContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) (err error) {
+	err = checkDestination(destPtr)
+	if err != nil {
+		return err
 	}
-	destType = destType.Elem()
 
-	rows, err := tx.Query(query, args...)
+	opts, args := splitOptions(args)
+	c := m.config(prefix, opts)
+
+	// Check the struct before querying, so that an invalid one never reaches
+	// the database
+	err = m.checkLayout(reflect.TypeOf(destPtr).Elem(), c)
 	if err != nil {
 		return err
 	}
 
-	defer rows.Close()
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
 
-	if destType.Kind() == reflect.Struct {
-		if !rows.Next() {
-			return sql.ErrNoRows
+	scanned := 0
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = rowsError(scanned, closeErr)
+		}
+	}()
+
+	scanned, err = m.scanRows(ctx, rows, destPtr, c)
+	return err
+}
+
+// SelectOne is like the package-level SelectOne, but uses the configuration of
+// the Mapper.
+func (m *Mapper) SelectOne(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	return m.SelectOneContext(context.Background(), queryerWithoutContext{tx}, destPtr, prefix, query, args...)
+}
+
+// SelectOneContext is like the package-level SelectOneContext, but uses the
+// configuration of the Mapper.
+func (m *Mapper) SelectOneContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) error {
+	err := checkSingleDestination(destPtr)
+	if err != nil {
+		return err
+	}
+
+	return m.SelectContext(ctx, tx, destPtr, prefix, query, append(args[:len(args):len(args)], withSingleRow())...)
+}
+
+// SelectOptional is like the package-level SelectOptional, but uses the
+// configuration of the Mapper.
+func (m *Mapper) SelectOptional(tx Queryer, destPtr interface{}, prefix string, query string, args ...interface{}) (bool, error) {
+	return m.SelectOptionalContext(context.Background(), queryerWithoutContext{tx}, destPtr, prefix, query, args...)
+}
+
+// SelectOptionalContext is like the package-level SelectOptionalContext, but
+// uses the configuration of the Mapper.
+func (m *Mapper) SelectOptionalContext(ctx context.Context, tx QueryerContext, destPtr interface{}, prefix string, query string, args ...interface{}) (bool, error) {
+	err := checkSingleDestination(destPtr)
+	if err != nil {
+		return false, err
+	}
+
+	err = m.SelectContext(ctx, tx, destPtr, prefix, query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Scan scans rows that have already been queried into destPtr, in the same way
+// as Select. It does not close rows.
+func (m *Mapper) Scan(rows *sql.Rows, destPtr interface{}, prefix string, opts ...Option) error {
+	return m.ScanContext(context.Background(), rows, destPtr, prefix, opts...)
+}
+
+// ScanContext is like Scan, but stops scanning between rows with the context's
+// error once ctx is done.
+func (m *Mapper) ScanContext(ctx context.Context, rows *sql.Rows, destPtr interface{}, prefix string, opts ...Option) error {
+	err := checkDestination(destPtr)
+	if err != nil {
+		return err
+	}
+
+	c := m.config(prefix, opts)
+
+	err = m.checkLayout(reflect.TypeOf(destPtr).Elem(), c)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.scanRows(ctx, rows, destPtr, c)
+	return err
+}
+
+func checkDestination(destPtr interface{}) error {
+	destType := reflect.TypeOf(destPtr)
+	if destType == nil || destType.Kind() != reflect.Ptr {
+		return fmt.Errorf("%w: pointer destination expected, got %v", ErrUnsupportedDestination, destType)
+	}
+
+	if reflect.ValueOf(destPtr).IsNil() {
+		return fmt.Errorf("%w: non-nil pointer destination expected, got nil %v", ErrUnsupportedDestination, destType)
+	}
+
+	destType = destType.Elem()
+
+	if !isRowType(destType) && !isMapDestination(destType) &&
+		(destType.Kind() != reflect.Slice || !isRowType(destType.Elem())) {
+		return fmt.Errorf("%w: destination must be pointer to struct, scalar, slice or map, got %v", ErrUnsupportedDestination, reflect.TypeOf(destPtr))
+	}
+
+	return nil
+}
+
+// checkLayout checks that the layout for destType, which must already have
+// been checked with checkDestination, can be scanned into with the
+// configuration c. This includes checking that the struct is valid, and that
+// the options given are supported for it.
+func (m *Mapper) checkLayout(destType reflect.Type, c config) error {
+	if isRowType(destType) {
+		return m.layout(destType, c.separator).err
+	}
+
+	var elemType reflect.Type
+	if isMapDestination(destType) {
+		elemType = mapElemType(destType)
+	} else {
+		elemType = destType.Elem()
+	}
+
+	layout := m.layout(elemType, c.separator)
+	if layout.err != nil {
+		return layout.err
+	}
+
+	if len(layout.collections) > 0 && c.maxRows > 0 {
+		return fmt.Errorf("%w: WithMaxRows cannot be used with collections in %v", ErrUnsupportedDestination, elemType)
+	}
+
+	if isMapDestination(destType) {
+		keyField, err := layout.mapKeyField(m, c.key)
+		if err != nil {
+			return err
 		}
 
-		s := For(destPtr, prefix)
-		return s.Scan(rows, destPtr)
+		if !isKeyConvertible(keyField.Type, destType.Key()) {
+			return fmt.Errorf("%w: key field '%s' of type %v cannot be used as map key of type %v", ErrUnsupportedDestination, keyField.Name, keyField.Type, destType.Key())
+		}
+	}
+
+	return nil
+}
+
+// isRowType reports whether a single row can be scanned into a value of type t.
+func isRowType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct || isScalar(t)
+}
+
+func checkSingleDestination(destPtr interface{}) error {
+	destType := reflect.TypeOf(destPtr)
+	if destType != nil && destType.Kind() == reflect.Ptr && !isRowType(destType.Elem()) {
+		return fmt.Errorf("%w: single row destination expected, got %v", ErrUnsupportedDestination, destType)
+	}
+
+	return nil
+}
+
+// scanRows scans rows into destPtr, which must already have been checked with
+// checkDestination and checkLayout, and returns the number of rows scanned.
+func (m *Mapper) scanRows(ctx context.Context, rows *sql.Rows, destPtr interface{}, c config) (int, error) {
+	destType := reflect.TypeOf(destPtr).Elem()
+
+	if isMapDestination(destType) {
+		return m.scanMap(ctx, rows, destPtr, c)
+	}
+
+	if isRowType(destType) {
+		s := m.forConfig(destType, c)
 
-	} else if destType.Kind() == reflect.Slice {
-		elemType := destType.Elem()
-		elemDest := reflect.New(elemType)
-		resultValue := reflect.New(destType)
+		if len(s.layout.collections) > 0 {
+			return m.scanAggregatedRow(ctx, rows, destPtr, c)
+		}
 
-		s := For(elemDest.Interface(), prefix)
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return 0, rowsError(0, err)
+			}
+			return 0, sql.ErrNoRows
+		}
 
-		for rows.Next() {
-			err := s.Scan(rows, elemDest.Interface())
-			if err != nil {
-				return err
+		err := s.ScanContext(ctx, rows, destPtr)
+		if err != nil {
+			return 0, err
+		}
+
+		if c.singleRow {
+			if rows.Next() {
+				return 1, ErrTooManyRows
+			}
+			if err := rows.Err(); err != nil {
+				return 1, rowsError(1, err)
 			}
+		}
+
+		return 1, nil
+	}
+
+	elemType := destType.Elem()
+	elemDest := reflect.New(elemType)
+	resultValue := reflect.New(destType)
+
+	s := m.forConfig(elemType, c)
+
+	scanned := 0
+
+	var agg *aggregator
+	if len(s.layout.collections) > 0 {
+		agg = newAggregator(s.layout)
+	}
+
+	for (c.maxRows <= 0 || scanned < c.maxRows) && rows.Next() {
+		err := s.ScanContext(ctx, rows, elemDest.Interface())
+		if err != nil {
+			return scanned, err
+		}
 
+		if c.keyField != nil && s.isNull(c.keyField) && !isNilPtr(elemDest.Elem()) {
+			return scanned, fmt.Errorf("%w: key field '%s' is NULL or missing (row %d)", ErrNullKey, c.keyField.Name, scanned)
+		}
+
+		if agg != nil {
+			agg.add(resultValue.Elem(), elemDest.Elem())
+		} else {
 			resultValue.Elem().Set(reflect.Append(resultValue.Elem(), elemDest.Elem()))
-			elemDest = reflect.New(elemType)
 		}
 
-		reflect.ValueOf(destPtr).Elem().Set(resultValue.Elem())
+		elemDest = reflect.New(elemType)
+		scanned++
+	}
 
-		return nil
+	if err := rows.Err(); err != nil {
+		return scanned, rowsError(scanned, err)
 	}
 
-	return fmt.Errorf("destination must be pointer to struct or slice")
+	reflect.ValueOf(destPtr).Elem().Set(resultValue.Elem())
+
+	return scanned, nil
+}
+
+// isNilPtr reports whether v is a nil pointer, as left for a row whose columns
+// are all NULL.
+func isNilPtr(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// scanAggregatedRow scans rows into destPtr, a pointer to a struct with
+// collections, by aggregating all rows and then taking the first element.
+func (m *Mapper) scanAggregatedRow(ctx context.Context, rows *sql.Rows, destPtr interface{}, c config) (int, error) {
+	resultPtr := reflect.New(reflect.SliceOf(reflect.TypeOf(destPtr).Elem()))
+
+	c.maxRows = 0
+
+	scanned, err := m.scanRows(ctx, rows, resultPtr.Interface(), c)
+	if err != nil {
+		return scanned, err
+	}
+
+	result := resultPtr.Elem()
+	if result.Len() == 0 {
+		return scanned, sql.ErrNoRows
+	}
+	if c.singleRow && result.Len() > 1 {
+		return scanned, ErrTooManyRows
+	}
+
+	reflect.ValueOf(destPtr).Elem().Set(result.Index(0))
+
+	return scanned, nil
 }
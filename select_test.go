@@ -1,10 +1,13 @@
 package structscanner
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestSelect(t *testing.T) {
@@ -127,4 +130,1563 @@ func TestSelect(t *testing.T) {
 			t.Errorf("Expected no results but got %d", actual)
 		}
 	})
+	t.Run("passes context through to QueryContext", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT ? AS "value"`
+
+		dbMock.
+			ExpectQuery(query).
+			WithArgs("some string").
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string"),
+			)
+
+		type contextKey struct{}
+		ctx := context.WithValue(context.Background(), contextKey{}, "marker")
+		tx := &contextRecorder{QueryerContext: db}
+
+		err := SelectContext(ctx, tx, &result, "", query, "some string")
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := 1, len(result); expected != actual {
+			t.Fatalf("Expected %d results but got %d", expected, actual)
+		}
+		if tx.ctx == nil || tx.ctx.Value(contextKey{}) != "marker" {
+			t.Errorf("Expected context to be passed to QueryContext but got %v", tx.ctx)
+		}
+	})
+
+	t.Run("returns context error when context is cancelled before querying", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := SelectContext(ctx, db, &result, "", `SELECT 1 AS "value"`)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled but got: %v", err)
+		}
+	})
+
+	t.Run("stops scanning between rows when context is cancelled", func(t *testing.T) {
+
+		var result []struct {
+			Value cancellingScanner `db:"value"`
+		}
+
+		query := `SELECT value FROM values`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("value 1").
+					AddRow("value 2").
+					AddRow("value 3"),
+			)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		scans := 0
+		cancelOnScan = func() {
+			scans++
+			cancel()
+		}
+		defer func() { cancelOnScan = nil }()
+
+		err := SelectContext(ctx, db, &result, "", query)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled but got: %v", err)
+		}
+		if expected, actual := 1, scans; expected != actual {
+			t.Errorf("Expected scanning to stop after %d row but scanned %d", expected, actual)
+		}
+		if result != nil {
+			t.Errorf("Expected destination not to be set but got %v", result)
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination when destination is not a pointer", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		err := Select(db, result, "", `SELECT 1 AS "value"`)
+
+		if !errors.Is(err, ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination without querying when destination is a nil pointer", func(t *testing.T) {
+
+		type Value struct {
+			Value string `db:"value"`
+		}
+
+		var structPtr *Value
+		var slicePtr *[]Value
+		var mapPtr *map[string]Value
+
+		for _, destPtr := range []interface{}{structPtr, slicePtr, mapPtr} {
+			err := Select(db, destPtr, "", `SELECT 1 AS "value"`)
+
+			if !errors.Is(err, ErrUnsupportedDestination) {
+				t.Errorf("Expected ErrUnsupportedDestination for %T but got: %v", destPtr, err)
+			}
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination when destination is not a struct or slice", func(t *testing.T) {
+
+		var result map[string]string
+
+		err := Select(db, &result, "", `SELECT 1 AS "value"`)
+
+		if !errors.Is(err, ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+		}
+	})
+
+	t.Run("returns a MappingError when a column has no destination field", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT 1 AS "prefix.value", 2 AS "prefix.other"`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"prefix.value", "prefix.other"}).
+					AddRow("1", "2"),
+			)
+
+		err := Select(db, &result, "prefix", query)
+
+		var mappingErr *MappingError
+		if !errors.As(err, &mappingErr) {
+			t.Fatalf("Expected a MappingError but got: %v", err)
+		}
+
+		if expected, actual := "prefix.other", mappingErr.Column; expected != actual {
+			t.Errorf("Expected column '%s' but got '%s'", expected, actual)
+		}
+	})
+	t.Run("returns iteration errors with the number of rows scanned", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT "value" FROM values`
+		rowErr := errors.New("connection reset")
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string 1").
+					AddRow("some string 2").
+					RowError(1, rowErr),
+			)
+
+		err := Select(db, &result, "", query)
+
+		if !errors.Is(err, rowErr) {
+			t.Fatalf("Expected row error but got: %v", err)
+		}
+		if expected, actual := "after scanning 1 rows: connection reset", err.Error(); expected != actual {
+			t.Errorf("Expected error '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("returns close errors", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT "value" FROM values`
+		closeErr := errors.New("close failed")
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string").
+					CloseError(closeErr),
+			)
+
+		err := Select(db, &result, "", query)
+
+		if !errors.Is(err, closeErr) {
+			t.Fatalf("Expected close error but got: %v", err)
+		}
+	})
+
+	t.Run("returns iteration error rather than ErrNoRows when destination is a single struct", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT "value" FROM values`
+		rowErr := errors.New("connection reset")
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string").
+					RowError(0, rowErr),
+			)
+
+		err := Select(db, &result, "", query)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("Expected row error but got ErrNoRows")
+		}
+		if !errors.Is(err, rowErr) {
+			t.Fatalf("Expected row error but got: %v", err)
+		}
+	})
+	t.Run("with options", func(t *testing.T) {
+
+		t.Run("ignores unknown columns for that call only", func(t *testing.T) {
+
+			var result struct {
+				Value string `db:"value"`
+			}
+
+			query := `SELECT ? AS value, ? AS other`
+
+			dbMock.
+				ExpectQuery(query).
+				WithArgs("value", "other").
+				WillReturnRows(
+					sqlmock.NewRows([]string{"value", "other"}).
+						AddRow("value", "other"),
+				)
+
+			err := Select(db, &result, "", query, "value", WithIgnoreUnknownColumns(), "other")
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "value", result.Value; expected != actual {
+				t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+			}
+
+			ss := For(&result, "")
+			if ss.config.ignoreUnknownColumns {
+				t.Errorf("Expected option not to affect later calls")
+			}
+		})
+
+		t.Run("limits the number of rows scanned", func(t *testing.T) {
+
+			var result []struct {
+				Value string `db:"value"`
+			}
+
+			query := `SELECT value FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"value"}).
+						AddRow("some string 1").
+						AddRow("some string 2").
+						AddRow("some string 3"),
+				)
+
+			err := Select(db, &result, "", query, WithMaxRows(2))
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Errorf("Expected %d results but got %d", expected, actual)
+			}
+		})
+
+		t.Run("overrides the separator", func(t *testing.T) {
+
+			var result struct {
+				Value  string `db:"value"`
+				Nested struct {
+					Inner struct {
+						Value string `db:"value"`
+					} `db:"inner"`
+				} `db:"nested"`
+			}
+
+			query := `SELECT value AS p__value, nested_value AS p__nested__inner__value`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"p__value", "p__nested__inner__value"}).
+						AddRow("value", "nested value"),
+				)
+
+			err := Select(db, &result, "p", query, WithSeparator("__"))
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "value", result.Value; expected != actual {
+				t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "nested value", result.Nested.Inner.Value; expected != actual {
+				t.Errorf("Expected nested value '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("overrides the prefix", func(t *testing.T) {
+
+			var result struct {
+				Value string `db:"value"`
+			}
+
+			query := `SELECT value AS "p.value"`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"p.value"}).
+						AddRow("value"),
+				)
+
+			err := Select(db, &result, "", query, WithPrefix("p"))
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "value", result.Value; expected != actual {
+				t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+	t.Run("with scalar destinations", func(t *testing.T) {
+
+		t.Run("maps single column to scalar", func(t *testing.T) {
+
+			var result int64
+
+			query := `SELECT COUNT(*) FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"COUNT(*)"}).
+						AddRow(42),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := int64(42), result; expected != actual {
+				t.Errorf("Expected %d but got %d", expected, actual)
+			}
+		})
+
+		t.Run("maps single column to time", func(t *testing.T) {
+
+			var result time.Time
+
+			query := `SELECT NOW()`
+			now := time.Date(2022, 2, 11, 12, 13, 14, 0, time.UTC)
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"NOW()"}).
+						AddRow(now),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := now, result; expected != actual {
+				t.Errorf("Expected %v but got %v", expected, actual)
+			}
+		})
+
+		t.Run("maps single column to scanner", func(t *testing.T) {
+
+			var result sql.NullString
+
+			query := `SELECT name FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"name"}).
+						AddRow("some string"),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := (sql.NullString{String: "some string", Valid: true}), result; expected != actual {
+				t.Errorf("Expected %v but got %v", expected, actual)
+			}
+		})
+
+		t.Run("maps rows to slice of scalars with NULLs as zero values", func(t *testing.T) {
+
+			var result []string
+
+			query := `SELECT name FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"name"}).
+						AddRow("some string").
+						AddRow(nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "some string", result[0]; expected != actual {
+				t.Errorf("Expected result 0 to be '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "", result[1]; expected != actual {
+				t.Errorf("Expected result 1 to be '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("maps rows to slice of scalar pointers with NULLs as nil", func(t *testing.T) {
+
+			var result []*int64
+
+			query := `SELECT id FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id"}).
+						AddRow(1).
+						AddRow(nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if actual := result[0]; actual == nil || *actual != 1 {
+				t.Errorf("Expected result 0 to point to 1 but got %v", actual)
+			}
+			if actual := result[1]; actual != nil {
+				t.Errorf("Expected result 1 to be nil but got %v", *actual)
+			}
+		})
+
+		t.Run("returns a MappingError when there is more than one column", func(t *testing.T) {
+
+			var result int64
+
+			query := `SELECT id, name FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(1, "some string"),
+				)
+
+			err := Select(db, &result, "", query)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected a MappingError but got: %v", err)
+			}
+			if expected, actual := "name", mappingErr.Column; expected != actual {
+				t.Errorf("Expected column '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+	t.Run("with collections", func(t *testing.T) {
+
+		type Person struct {
+			ID   int64  `db:"id,pk"`
+			Name string `db:"name"`
+		}
+
+		type Team struct {
+			ID      int64    `db:"id,pk"`
+			Name    string   `db:"name"`
+			Members []Person `db:"p"`
+		}
+
+		type Organisation struct {
+			ID      int64     `db:"id,pk"`
+			Name    string    `db:"name"`
+			Members []*Person `db:"p"`
+		}
+
+		t.Run("merges rows with the same key and appends distinct children", func(t *testing.T) {
+
+			var result []Organisation
+
+			query := `SELECT o.id, o.name, p.id, p.name FROM organisations o LEFT JOIN people p`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "p.id", "p.name"}).
+						AddRow(1, "Org 1", 1, "Alice").
+						AddRow(1, "Org 1", 2, "Bob").
+						AddRow(2, "Org 2", nil, nil).
+						AddRow(1, "Org 1", 1, "Alice"),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "Org 1", result[0].Name; expected != actual {
+				t.Errorf("Expected result 0 to have name '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := 2, len(result[0].Members); expected != actual {
+				t.Fatalf("Expected result 0 to have %d members but got %d", expected, actual)
+			}
+			if expected, actual := "Bob", result[0].Members[1].Name; expected != actual {
+				t.Errorf("Expected member 1 to have name '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := 0, len(result[1].Members); expected != actual {
+				t.Errorf("Expected result 1 to have %d members but got %d", expected, actual)
+			}
+		})
+
+		t.Run("skips rows that are all NULL for pointer elements", func(t *testing.T) {
+
+			var result []*Organisation
+
+			query := `SELECT o.id, o.name, p.id, p.name FROM people p LEFT JOIN organisations o`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "p.id", "p.name"}).
+						AddRow(nil, nil, nil, nil).
+						AddRow(1, "Org 1", 1, "Alice").
+						AddRow(nil, nil, nil, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 1, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "Org 1", result[0].Name; expected != actual {
+				t.Errorf("Expected result 0 to have name '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("returns ErrUnsupportedDestination with WithMaxRows", func(t *testing.T) {
+
+			var result []Organisation
+
+			query := `SELECT o.id, o.name, p.id, p.name FROM organisations o JOIN people p`
+
+			err := Select(db, &result, "", query, WithMaxRows(1))
+
+			if !errors.Is(err, ErrUnsupportedDestination) {
+				t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+			}
+		})
+
+		t.Run("merges nested collections", func(t *testing.T) {
+
+			var result struct {
+				ID    int64  `db:"id,pk"`
+				Teams []Team `db:"t"`
+			}
+
+			query := `SELECT o.id, t.id, t.name, p.id, p.name FROM organisations o JOIN teams t JOIN people p`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "t.id", "t.name", "t.p.id", "t.p.name"}).
+						AddRow(1, 1, "Team 1", 1, "Alice").
+						AddRow(1, 2, "Team 2", 2, "Bob").
+						AddRow(1, 1, "Team 1", 3, "Carol").
+						AddRow(1, 2, "Team 2", nil, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result.Teams); expected != actual {
+				t.Fatalf("Expected %d teams but got %d", expected, actual)
+			}
+			if expected, actual := 2, len(result.Teams[0].Members); expected != actual {
+				t.Fatalf("Expected team 0 to have %d members but got %d", expected, actual)
+			}
+			if expected, actual := "Carol", result.Teams[0].Members[1].Name; expected != actual {
+				t.Errorf("Expected team 0 member 1 to have name '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := 1, len(result.Teams[1].Members); expected != actual {
+				t.Errorf("Expected team 1 to have %d members but got %d", expected, actual)
+			}
+		})
+
+		t.Run("returns ErrTooManyRows from SelectOne when there is more than one parent", func(t *testing.T) {
+
+			var result Organisation
+
+			query := `SELECT o.id, o.name, p.id, p.name FROM organisations o JOIN people p`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "p.id", "p.name"}).
+						AddRow(1, "Org 1", 1, "Alice").
+						AddRow(1, "Org 1", 2, "Bob").
+						AddRow(2, "Org 2", 3, "Carol"),
+				)
+
+			err := SelectOne(db, &result, "", query)
+
+			if !errors.Is(err, ErrTooManyRows) {
+				t.Errorf("Expected ErrTooManyRows but got: %v", err)
+			}
+		})
+	})
+	t.Run("with map destinations", func(t *testing.T) {
+
+		type Fruit struct {
+			ID     int64  `db:"id,key"`
+			Name   string `db:"name"`
+			Colour string `db:"colour"`
+		}
+
+		t.Run("maps rows by key column", func(t *testing.T) {
+
+			var result map[int64]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(1, "apple", "red").
+						AddRow(2, "banana", "yellow"),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "banana", result[2].Name; expected != actual {
+				t.Errorf("Expected result 2 to have name '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("maps rows to pointers by named field", func(t *testing.T) {
+
+			var result map[string]*Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(1, "apple", "red").
+						AddRow(2, "banana", "yellow"),
+				)
+
+			err := Select(db, &result, "", query, WithKey("Name"))
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if actual := result["apple"]; actual == nil || actual.ID != 1 {
+				t.Errorf("Expected apple to have ID 1 but got %v", actual)
+			}
+		})
+
+		t.Run("groups rows into slices", func(t *testing.T) {
+
+			var result map[string][]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(1, "apple", "red").
+						AddRow(2, "banana", "yellow").
+						AddRow(3, "cherry", "red"),
+				)
+
+			err := Select(db, &result, "", query, WithKey("colour"))
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result["red"]); expected != actual {
+				t.Fatalf("Expected %d red fruits but got %d", expected, actual)
+			}
+			if expected, actual := "cherry", result["red"][1].Name; expected != actual {
+				t.Errorf("Expected red fruit 1 to have name '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("returns ErrDuplicateKey when two rows have the same key", func(t *testing.T) {
+
+			var result map[string]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(1, "apple", "red").
+						AddRow(3, "cherry", "red"),
+				)
+
+			err := Select(db, &result, "", query, WithKey("colour"))
+
+			if !errors.Is(err, ErrDuplicateKey) {
+				t.Errorf("Expected ErrDuplicateKey but got: %v", err)
+			}
+		})
+
+		t.Run("returns ErrUnsupportedDestination when the key type does not match", func(t *testing.T) {
+
+			var result map[string]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			err := Select(db, &result, "", query)
+
+			if !errors.Is(err, ErrUnsupportedDestination) {
+				t.Errorf("Expected ErrUnsupportedDestination but got: %v (result %v)", err, result)
+			}
+		})
+
+		t.Run("converts keys of the same kind", func(t *testing.T) {
+
+			var result map[int32]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(65, "apple", "red"),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "apple", result[65].Name; expected != actual {
+				t.Errorf("Expected result 65 to have name '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("skips rows that are all NULL for pointer values", func(t *testing.T) {
+
+			var result map[int64]*Fruit
+
+			query := `SELECT f.id, f.name, f.colour FROM baskets b LEFT JOIN fruits f`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(nil, nil, nil).
+						AddRow(1, "apple", "red"),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 1, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if actual := result[1]; actual == nil || actual.Name != "apple" {
+				t.Errorf("Expected result 1 to be apple but got %v", actual)
+			}
+		})
+
+		t.Run("returns ErrNullKey when a non-pointer key is NULL", func(t *testing.T) {
+
+			var result map[int64]Fruit
+
+			query := `SELECT id, name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "colour"}).
+						AddRow(0, "apple", "red").
+						AddRow(nil, "banana", "yellow"),
+				)
+
+			err := Select(db, &result, "", query)
+
+			if !errors.Is(err, ErrNullKey) {
+				t.Errorf("Expected ErrNullKey but got: %v", err)
+			}
+		})
+
+		t.Run("returns ErrNullKey when the key column is missing", func(t *testing.T) {
+
+			var result map[int64]Fruit
+
+			query := `SELECT name, colour FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"name", "colour"}).
+						AddRow("apple", "red"),
+				)
+
+			err := Select(db, &result, "", query)
+
+			if !errors.Is(err, ErrNullKey) {
+				t.Errorf("Expected ErrNullKey but got: %v", err)
+			}
+		})
+
+		t.Run("returns ErrNullKey when a key is NULL", func(t *testing.T) {
+
+			var result map[int64]struct {
+				ID   *int64 `db:"id,key"`
+				Name string `db:"name"`
+			}
+
+			query := `SELECT id, name FROM fruits`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(0, "apple").
+						AddRow(nil, "banana"),
+				)
+
+			err := Select(db, &result, "", query)
+
+			if !errors.Is(err, ErrNullKey) {
+				t.Errorf("Expected ErrNullKey but got: %v", err)
+			}
+		})
+	})
+	t.Run("returns ErrInvalidStruct without querying when destination is invalid", func(t *testing.T) {
+
+		var result []struct {
+			ID      int64 `db:"id"`
+			OtherID int64 `db:"id"`
+		}
+
+		query := `SELECT id FROM values`
+
+		err := Select(db, &result, "", query)
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", err)
+		}
+	})
+	t.Run("with rest fields", func(t *testing.T) {
+
+		t.Run("collects unmapped columns", func(t *testing.T) {
+
+			var result []struct {
+				ID   int64          `db:"id"`
+				Rest map[string]any `db:",rest"`
+			}
+
+			query := `SELECT id, COUNT(*) AS total, NULL AS missing FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "total", "missing"}).
+						AddRow(1, 10, nil).
+						AddRow(2, 20, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := int64(20), result[1].Rest["total"]; expected != actual {
+				t.Errorf("Expected total %v but got %v", expected, actual)
+			}
+			if actual, ok := result[1].Rest["missing"]; !ok || actual != nil {
+				t.Errorf("Expected missing to be present and nil but got %v", actual)
+			}
+			if _, ok := result[1].Rest["id"]; ok {
+				t.Errorf("Expected mapped column not to be collected")
+			}
+		})
+
+		t.Run("collects unmapped columns as raw bytes", func(t *testing.T) {
+
+			var result []struct {
+				ID   int64                   `db:"id"`
+				Rest map[string]sql.RawBytes `db:",rest"`
+			}
+
+			query := `SELECT id, name FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(1, []byte("apple")).
+						AddRow(2, []byte("banana")),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := "apple", string(result[0].Rest["name"]); expected != actual {
+				t.Errorf("Expected result 0 name '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "banana", string(result[1].Rest["name"]); expected != actual {
+				t.Errorf("Expected result 1 name '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+
+	t.Run("with prefix maps", func(t *testing.T) {
+
+		t.Run("collects columns under the field's name", func(t *testing.T) {
+
+			var result []struct {
+				ID    int64             `db:"id"`
+				Attrs map[string]string `db:"attr,prefixmap"`
+			}
+
+			query := `SELECT id, colour AS "attr.colour", size AS "attr.size" FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "attr.colour", "attr.size"}).
+						AddRow(1, "red", "large").
+						AddRow(2, "blue", nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := "red", result[0].Attrs["colour"]; expected != actual {
+				t.Errorf("Expected result 0 colour '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "large", result[0].Attrs["size"]; expected != actual {
+				t.Errorf("Expected result 0 size '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "blue", result[1].Attrs["colour"]; expected != actual {
+				t.Errorf("Expected result 1 colour '%s' but got '%s'", expected, actual)
+			}
+			if _, ok := result[1].Attrs["size"]; ok {
+				t.Errorf("Expected NULL size to be left out")
+			}
+		})
+
+		t.Run("converts values to the map's value type", func(t *testing.T) {
+
+			var result struct {
+				Item struct {
+					ID     int64            `db:"id"`
+					Counts map[string]int64 `db:"count,prefixmap"`
+				} `db:"item"`
+			}
+
+			query := `SELECT i.id, i.views, i.likes FROM items i`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"i.item.id", "i.item.count.views", "i.item.count.likes"}).
+						AddRow(1, "10", 3),
+				)
+
+			err := SelectOne(db, &result, "i", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := int64(10), result.Item.Counts["views"]; expected != actual {
+				t.Errorf("Expected views %d but got %d", expected, actual)
+			}
+			if expected, actual := int64(3), result.Item.Counts["likes"]; expected != actual {
+				t.Errorf("Expected likes %d but got %d", expected, actual)
+			}
+		})
+
+		t.Run("leaves the map nil when there are no columns", func(t *testing.T) {
+
+			var result struct {
+				ID    int64             `db:"id"`
+				Attrs map[string]string `db:"attr,prefixmap"`
+			}
+
+			query := `SELECT id FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id"}).
+						AddRow(1),
+				)
+
+			err := SelectOne(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if result.Attrs != nil {
+				t.Errorf("Expected nil map but got %v", result.Attrs)
+			}
+		})
+	})
+
+	t.Run("with JSON columns", func(t *testing.T) {
+
+		type Meta struct {
+			Colour string `json:"colour"`
+			Size   int    `json:"size"`
+		}
+
+		t.Run("decodes JSON into fields of any type", func(t *testing.T) {
+
+			var result []struct {
+				ID   int64          `db:"id"`
+				Meta *Meta          `db:"meta,json"`
+				Tags []string       `db:"tags,json"`
+				Info map[string]any `db:"info,json"`
+			}
+
+			query := `SELECT id, meta, tags, info FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "meta", "tags", "info"}).
+						AddRow(1, `{"colour":"red","size":3}`, []byte(`["a","b"]`), `{"ok":true}`).
+						AddRow(2, nil, nil, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := (Meta{"red", 3}), *result[0].Meta; expected != actual {
+				t.Errorf("Expected meta %+v but got %+v", expected, actual)
+			}
+			if expected, actual := []string{"a", "b"}, result[0].Tags; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected tags %v but got %v", expected, actual)
+			}
+			if expected, actual := true, result[0].Info["ok"]; expected != actual {
+				t.Errorf("Expected info ok %v but got %v", expected, actual)
+			}
+			if result[1].Meta != nil || result[1].Tags != nil || result[1].Info != nil {
+				t.Errorf("Expected NULLs to leave zero values but got %+v", result[1])
+			}
+		})
+
+		t.Run("returns MappingError for invalid JSON", func(t *testing.T) {
+
+			var result struct {
+				Item struct {
+					Meta Meta `db:"meta,json"`
+				} `db:"item"`
+			}
+
+			query := `SELECT meta FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"item.meta"}).
+						AddRow(`{"colour":`),
+				)
+
+			err := SelectOne(db, &result, "", query)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected MappingError but got: %v", err)
+			}
+			if expected, actual := "item.meta", mappingErr.Column; expected != actual {
+				t.Errorf("Expected column '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "Item.Meta", mappingErr.Field; expected != actual {
+				t.Errorf("Expected field '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+
+	t.Run("with delimited string columns", func(t *testing.T) {
+
+		t.Run("splits values into slices", func(t *testing.T) {
+
+			var result []struct {
+				ID     int64      `db:"id"`
+				Tags   []string   `db:"tags,split=|"`
+				Scores []int64    `db:"scores,split=,"`
+				Ratios []*float64 `db:"ratios,split=;"`
+			}
+
+			query := `SELECT id, GROUP_CONCAT(tag SEPARATOR '|') AS tags, GROUP_CONCAT(score) AS scores, ratios FROM values GROUP BY id`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "tags", "scores", "ratios"}).
+						AddRow(1, "red|green", []byte("1,2,3"), "0.5").
+						AddRow(2, nil, "", nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := []string{"red", "green"}, result[0].Tags; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected tags %v but got %v", expected, actual)
+			}
+			if expected, actual := []int64{1, 2, 3}, result[0].Scores; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected scores %v but got %v", expected, actual)
+			}
+			if len(result[0].Ratios) != 1 || *result[0].Ratios[0] != 0.5 {
+				t.Errorf("Expected ratios [0.5] but got %v", result[0].Ratios)
+			}
+			if result[1].Tags != nil || result[1].Scores != nil || result[1].Ratios != nil {
+				t.Errorf("Expected NULL and empty values to give nil slices but got %+v", result[1])
+			}
+		})
+
+		t.Run("returns MappingError for unconvertible elements", func(t *testing.T) {
+
+			var result struct {
+				Scores []int64 `db:"scores,split=,"`
+			}
+
+			query := `SELECT scores FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"scores"}).
+						AddRow("1,two"),
+				)
+
+			err := SelectOne(db, &result, "", query)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected MappingError but got: %v", err)
+			}
+			if expected, actual := "Scores", mappingErr.Field; expected != actual {
+				t.Errorf("Expected field '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+
+	t.Run("with array columns", func(t *testing.T) {
+
+		t.Run("parses arrays into slices", func(t *testing.T) {
+
+			var result []struct {
+				ID     int64     `db:"id"`
+				Tags   []string  `db:"tags"`
+				Scores []*int64  `db:"scores"`
+				Grid   [][]int32 `db:"grid"`
+			}
+
+			query := `SELECT id, tags, scores, grid FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "tags", "scores", "grid"}).
+						AddRow(1, `{red,"dark green"}`, []byte(`{1,NULL}`), `{{1,2},{3,4}}`).
+						AddRow(2, nil, `{}`, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+			if expected, actual := []string{"red", "dark green"}, result[0].Tags; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected tags %v but got %v", expected, actual)
+			}
+			if scores := result[0].Scores; len(scores) != 2 || *scores[0] != 1 || scores[1] != nil {
+				t.Errorf("Expected scores [1 nil] but got %v", scores)
+			}
+			if expected, actual := [][]int32{{1, 2}, {3, 4}}, result[0].Grid; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected grid %v but got %v", expected, actual)
+			}
+			if result[1].Tags != nil {
+				t.Errorf("Expected NULL array to give nil slice but got %v", result[1].Tags)
+			}
+			if actual := result[1].Scores; actual == nil || len(actual) != 0 {
+				t.Errorf("Expected empty array to give empty slice but got %v", actual)
+			}
+		})
+
+		t.Run("returns MappingError for invalid arrays", func(t *testing.T) {
+
+			var result struct {
+				Tags []string `db:"tags"`
+			}
+
+			query := `SELECT tags FROM values`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"tags"}).
+						AddRow(`{a,b`),
+				)
+
+			err := SelectOne(db, &result, "", query)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected MappingError but got: %v", err)
+			}
+		})
+	})
+
+	t.Run("with composite columns", func(t *testing.T) {
+
+		type Person struct {
+			ID       int64    `db:"id"`
+			Name     string   `db:"name"`
+			Active   bool     `db:"active"`
+			Nickname *string  `db:"nickname"`
+			Tags     []string `db:"tags"`
+		}
+
+		t.Run("parses row literals into nested structs", func(t *testing.T) {
+
+			var result []struct {
+				ID     int64   `db:"id"`
+				Person *Person `db:"person,composite"`
+			}
+
+			query := `SELECT t.id, row(p.*) AS person FROM teams t LEFT JOIN people p ON p.id = t.leader_id`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"id", "person"}).
+						AddRow(1, `(1,"Alice Smith",t,,"{a,b}")`).
+						AddRow(2, nil),
+				)
+
+			err := Select(db, &result, "", query)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if expected, actual := 2, len(result); expected != actual {
+				t.Fatalf("Expected %d results but got %d", expected, actual)
+			}
+
+			person := result[0].Person
+			if person == nil {
+				t.Fatalf("Expected person to be set")
+			}
+			if expected, actual := int64(1), person.ID; expected != actual {
+				t.Errorf("Expected ID %d but got %d", expected, actual)
+			}
+			if expected, actual := "Alice Smith", person.Name; expected != actual {
+				t.Errorf("Expected name '%s' but got '%s'", expected, actual)
+			}
+			if !person.Active {
+				t.Errorf("Expected active to be true")
+			}
+			if person.Nickname != nil {
+				t.Errorf("Expected NULL nickname to be nil but got '%s'", *person.Nickname)
+			}
+			if expected, actual := []string{"a", "b"}, person.Tags; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected tags %v but got %v", expected, actual)
+			}
+			if result[1].Person != nil {
+				t.Errorf("Expected NULL person to be nil but got %+v", result[1].Person)
+			}
+		})
+
+		t.Run("returns MappingError for the wrong number of elements", func(t *testing.T) {
+
+			var result struct {
+				Person Person `db:"person,composite"`
+			}
+
+			query := `SELECT row(p.*) AS person FROM people p`
+
+			dbMock.
+				ExpectQuery(query).
+				WillReturnRows(
+					sqlmock.NewRows([]string{"person"}).
+						AddRow(`(1,Alice)`),
+				)
+
+			err := SelectOne(db, &result, "", query)
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected MappingError but got: %v", err)
+			}
+			if expected, actual := "Person", mappingErr.Field; expected != actual {
+				t.Errorf("Expected field '%s' but got '%s'", expected, actual)
+			}
+		})
+	})
+}
+
+func TestSelectOne(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("maps a single row to struct", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value FROM values WHERE id = ?`
+
+		dbMock.
+			ExpectQuery(query).
+			WithArgs(1).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string"),
+			)
+
+		err := SelectOne(db, &result, "", query, 1)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if expected, actual := "some string", result.Value; expected != actual {
+			t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("returns ErrTooManyRows when there is more than one row", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value FROM values`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string 1").
+					AddRow("some string 2"),
+			)
+
+		err := SelectOne(db, &result, "", query)
+
+		if !errors.Is(err, ErrTooManyRows) {
+			t.Errorf("Expected ErrTooManyRows but got: %v", err)
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination when destination is a slice", func(t *testing.T) {
+
+		var result []struct {
+			Value string `db:"value"`
+		}
+
+		err := SelectOne(db, &result, "", `SELECT value FROM values`)
+
+		if !errors.Is(err, ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+		}
+	})
+}
+
+func TestSelectOptional(t *testing.T) {
+
+	db, dbMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error setting up mock DB: %v", err)
+	}
+	defer db.Close()
+
+	t.Run("reports found when there is a row", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value FROM values`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}).
+					AddRow("some string"),
+			)
+
+		found, err := SelectOptional(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if !found {
+			t.Errorf("Expected row to be found but wasn’t")
+		}
+		if expected, actual := "some string", result.Value; expected != actual {
+			t.Errorf("Expected value '%s' but got '%s'", expected, actual)
+		}
+	})
+
+	t.Run("reports not found when there are no rows", func(t *testing.T) {
+
+		var result struct {
+			Value string `db:"value"`
+		}
+
+		query := `SELECT value FROM values`
+
+		dbMock.
+			ExpectQuery(query).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"value"}),
+			)
+
+		found, err := SelectOptional(db, &result, "", query)
+		if err != nil {
+			t.Fatalf("Expected success but got error: %v", err)
+		}
+
+		if found {
+			t.Errorf("Expected no row to be found but was")
+		}
+	})
+}
+
+// contextRecorder is a QueryerContext that records the context its queries
+// are performed with.
+type contextRecorder struct {
+	QueryerContext
+	ctx context.Context
+}
+
+func (r *contextRecorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.ctx = ctx
+	return r.QueryerContext.QueryContext(ctx, query, args...)
+}
+
+// cancelOnScan, if set, is called whenever a cancellingScanner is scanned.
+var cancelOnScan func()
+
+// cancellingScanner is a string that calls cancelOnScan when it is scanned,
+// to cancel a context part way through a query.
+type cancellingScanner string
+
+func (s *cancellingScanner) Scan(src interface{}) error {
+	if cancelOnScan != nil {
+		cancelOnScan()
+	}
+
+	switch v := src.(type) {
+	case string:
+		*s = cancellingScanner(v)
+	case []byte:
+		*s = cancellingScanner(v)
+	}
+
+	return nil
 }
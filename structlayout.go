@@ -2,19 +2,85 @@ package structscanner
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"reflect"
-	"sync"
 	"time"
 )
 
-var cachedLayouts = &sync.Map{}
+var (
+	scannerInterface = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType         = reflect.TypeOf((*time.Time)(nil)).Elem()
+)
 
 type structLayout struct {
+	structType   reflect.Type
+	separator    string
 	fields       []field
 	fieldsByName map[string]*field
+
+	// collections holds all slice-of-struct fields, with parents before their
+	// children. rootCollections holds those belonging to the root struct.
+	collections     []*collection
+	rootCollections []*collection
+
+	// keyFields holds the fields identifying distinct elements at each level
+	// of aggregation, with the root struct at the nil key.
+	keyFields map[*collection][]*field
+
+	// prefixMaps holds the fields with the `prefixmap` tag option.
+	prefixMaps []*field
+
+	// restField is the field with the `rest` tag option, if any.
+	restField *field
+
+	// err holds any problems found with the struct while building the layout,
+	// which prevent it from being scanned into.
+	err error
+
+	// scalar is set when values of structType are scanned directly from a
+	// single column, in which case fields holds a single unnamed field.
+	scalar bool
 }
 
-func findStructFields(t reflect.Type, parentPath string, parentFieldIndex []int, fields *[]field) {
+// isScalar reports whether values of type t (or of the type it points to) are
+// scanned directly from a single column, rather than having their fields
+// mapped from columns.
+func isScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return reflect.PtrTo(t).Implements(scannerInterface) || t == timeType
+
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8 || reflect.PtrTo(t).Implements(scannerInterface)
+
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Map, reflect.UnsafePointer:
+		return reflect.PtrTo(t).Implements(scannerInterface)
+	}
+
+	return true
+}
+
+// isCollection reports whether t is a slice of structs (or pointers to structs)
+// whose elements are aggregated from rows.
+func isCollection(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || isScalar(t) {
+		return false
+	}
+
+	elemType := t.Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	return elemType.Kind() == reflect.Struct && !isScalar(elemType)
+}
+
+func findStructFields(m *Mapper, sl *structLayout, t reflect.Type, parentPath string, parentFieldIndex []int, coll *collection, depth int) {
 	isPtr := t.Kind() == reflect.Ptr
 	if isPtr {
 		t = t.Elem()
@@ -22,57 +88,350 @@ func findStructFields(t reflect.Type, parentPath string, parentFieldIndex []int,
 
 	fieldCount := t.NumField()
 
-	scannerInterface := reflect.TypeOf((*sql.Scanner)(nil)).Elem()
-	timeType := reflect.TypeOf((*time.Time)(nil)).Elem()
-
 	for i := 0; i < fieldCount; i++ {
 		f := t.Field(i)
 
-		tag := f.Tag.Get("db")
-		if tag == "" {
+		tag, tagged := f.Tag.Lookup(m.tagName())
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+
+		// Untagged embedded structs, and fields with the `inline` option, have
+		// their fields flattened into the parent's namespace
+		if (tag == "" && f.Anonymous) || opts.has("inline") {
+			flattenType := f.Type
+			if flattenType.Kind() == reflect.Ptr {
+				// Embedded pointers to unexported types can't be instantiated
+				if !f.IsExported() {
+					continue
+				}
+				flattenType = flattenType.Elem()
+			}
+
+			if flattenType.Kind() == reflect.Struct && !isScalar(flattenType) {
+				findStructFields(m, sl, f.Type, parentPath, appendIndex(parentFieldIndex, i), coll, depth+1)
+			}
+			continue
+		}
+
+		// Untagged fields are only mapped when there is a NameMapper
+		implicit := !tagged || tag == ""
+		if implicit && (m.NameMapper == nil || !f.IsExported()) {
 			continue
 		}
 
-		fieldPath := tag
+		if !f.IsExported() {
+			sl.invalid("field %s.%s has a `%s:` tag but is unexported", t.Name(), f.Name, m.tagName())
+			continue
+		}
+
+		if name == "" {
+			name = m.fieldName(f.Name)
+		}
+
+		fieldPath := name
 		if parentPath != "" {
-			fieldPath = parentPath + "." + fieldPath
+			fieldPath = parentPath + sl.separator + fieldPath
 		}
 
-		fieldIndex := make([]int, len(parentFieldIndex)+1)
-		copy(fieldIndex, parentFieldIndex)
-		fieldIndex[len(fieldIndex)-1] = f.Index[0]
+		fieldIndex := appendIndex(parentFieldIndex, i)
 
 		fieldType := f.Type
 		if fieldType.Kind() == reflect.Ptr {
 			fieldType = fieldType.Elem()
 		}
 
-		if fieldType.Kind() == reflect.Struct &&
-			!reflect.PtrTo(fieldType).Implements(scannerInterface) &&
-			fieldType != timeType {
+		if opts.has("rest") {
+			sl.setRestField(t, f, fieldIndex, parentPath != "" || coll != nil)
+			continue
+		}
 
-			findStructFields(f.Type, fieldPath, fieldIndex, fields)
+		if opts.has("prefixmap") {
+			sl.addPrefixMap(m, t, f, fieldPath, fieldIndex, coll)
+			continue
+		}
 
-		} else {
-			*fields = append(*fields, field{
+		scanType, decode := sl.decoder(m, t, f, fieldType, opts)
+
+		if decode == nil && fieldType.Kind() == reflect.Struct && !isScalar(fieldType) {
+
+			findStructFields(m, sl, f.Type, fieldPath, fieldIndex, coll, depth)
+
+		} else if decode == nil && isCollection(fieldType) {
+
+			c := &collection{
 				Name:    fieldPath,
 				Type:    fieldType,
 				Indices: fieldIndex,
+				Parent:  coll,
+			}
+
+			sl.collections = append(sl.collections, c)
+			if coll == nil {
+				sl.rootCollections = append(sl.rootCollections, c)
+			} else {
+				coll.Children = append(coll.Children, c)
+			}
+
+			findStructFields(m, sl, fieldType.Elem(), fieldPath, nil, c, depth)
+
+		} else if decode == nil && !isScannable(fieldType) {
+			if implicit {
+				continue
+			}
+
+			sl.invalid("field %s.%s has unsupported type %v", t.Name(), f.Name, f.Type)
+
+		} else {
+			var aliases []string
+			for _, alias := range opts.values("alias") {
+				if parentPath != "" {
+					alias = parentPath + sl.separator + alias
+				}
+				aliases = append(aliases, alias)
+			}
+
+			sl.fields = append(sl.fields, field{
+				Name:       fieldPath,
+				Aliases:    aliases,
+				Type:       fieldType,
+				ScanType:   scanType,
+				Decode:     decode,
+				Indices:    fieldIndex,
+				Collection: coll,
+				PrimaryKey: opts.has("pk"),
+				Key:        opts.has("key"),
+				Depth:      depth,
 			})
 		}
 	}
 }
 
-func newStructLayout(sType reflect.Type) *structLayout {
+func newStructLayout(sType reflect.Type, m *Mapper, separator string) *structLayout {
+	structType := sType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
 	sl := &structLayout{
+		structType:   structType,
+		separator:    separator,
 		fieldsByName: make(map[string]*field),
 	}
 
-	findStructFields(sType, "", nil, &sl.fields)
+	convert, converted := m.converters[structType]
+
+	if isScalar(structType) || converted {
+		sl.scalar = true
+		sl.fields = []field{{
+			Type: structType,
+		}}
+
+		if converted {
+			sl.fields[0].ScanType = reflect.TypeFor[any]()
+			sl.fields[0].Decode = convert
+		}
+
+		return sl
+	}
+
+	findStructFields(m, sl, sType, "", nil, nil, 0)
+	sl.removeShadowedFields()
+
+	if sl.err != nil {
+		return sl
+	}
 
 	for i := range sl.fields {
-		sl.fieldsByName[sl.fields[i].Name] = &sl.fields[i]
+		f := &sl.fields[i]
+
+		for _, column := range append([]string{f.Name}, f.Aliases...) {
+			name := m.normalise(column)
+
+			if other := sl.fieldsByName[name]; other != nil {
+				sl.invalid("column '%s' of field for '%s' is ambiguous with field for '%s'", column, f.Name, other.Name)
+				continue
+			}
+
+			sl.fieldsByName[name] = f
+		}
+	}
+
+	if sl.err != nil {
+		return sl
+	}
+
+	if len(sl.collections) > 0 {
+		sl.keyFields = make(map[*collection][]*field)
+		sl.findKeyFields(nil)
+		for _, c := range sl.collections {
+			sl.findKeyFields(c)
+		}
 	}
 
 	return sl
 }
+
+// appendIndex returns a copy of indices with i appended.
+func appendIndex(indices []int, i int) []int {
+	result := make([]int, len(indices)+1)
+	copy(result, indices)
+	result[len(result)-1] = i
+	return result
+}
+
+// setRestField sets the field with the `rest` tag option, checking that it is
+// a suitable map in the root struct.
+func (sl *structLayout) setRestField(t reflect.Type, f reflect.StructField, fieldIndex []int, nested bool) {
+	if nested {
+		sl.invalid("field %s.%s has the `rest` option but is not in the root struct", t.Name(), f.Name)
+		return
+	}
+
+	if sl.restField != nil {
+		sl.invalid("field %s.%s has the `rest` option but there is already a rest field", t.Name(), f.Name)
+		return
+	}
+
+	valueType := f.Type.Elem()
+	if f.Type.Kind() != reflect.Map || f.Type.Key().Kind() != reflect.String ||
+		(valueType != reflect.TypeFor[any]() && valueType != reflect.TypeFor[sql.RawBytes]() && valueType != reflect.TypeFor[[]byte]()) {
+
+		sl.invalid("field %s.%s has the `rest` option but is not map[string]any or map[string]sql.RawBytes", t.Name(), f.Name)
+		return
+	}
+
+	sl.restField = &field{
+		Type:    valueType,
+		Indices: fieldIndex,
+		Rest:    true,
+	}
+}
+
+// addPrefixMap adds a field with the `prefixmap` tag option, checking that it
+// is a suitable map. Values are converted with any converter registered for
+// the map's value type.
+func (sl *structLayout) addPrefixMap(m *Mapper, t reflect.Type, f reflect.StructField, fieldPath string, fieldIndex []int, coll *collection) {
+	if f.Type.Kind() != reflect.Map || f.Type.Key().Kind() != reflect.String {
+		sl.invalid("field %s.%s has the `prefixmap` option but is not a map from strings to a scannable type", t.Name(), f.Name)
+		return
+	}
+
+	valueType := f.Type.Elem()
+	convert, converted := m.converters[valueType]
+
+	if !converted && !isScannable(valueType) {
+		sl.invalid("field %s.%s has the `prefixmap` option but is not a map from strings to a scannable type", t.Name(), f.Name)
+		return
+	}
+
+	pm := &field{
+		Name:       fieldPath,
+		Type:       valueType,
+		Indices:    fieldIndex,
+		Collection: coll,
+		PrefixMap:  true,
+	}
+
+	if converted {
+		pm.ScanType = reflect.TypeFor[any]()
+		pm.Decode = convert
+	}
+
+	sl.prefixMaps = append(sl.prefixMaps, pm)
+}
+
+// isScannable reports whether a value can be scanned into a field of type t.
+func isScannable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.UnsafePointer:
+		return reflect.PtrTo(t).Implements(scannerInterface)
+	}
+
+	return true
+}
+
+// invalid records a problem with the struct that prevents it from being
+// scanned into.
+func (sl *structLayout) invalid(format string, args ...interface{}) {
+	sl.err = errors.Join(sl.err, fmt.Errorf("%w: %v: "+format, append([]interface{}{ErrInvalidStruct, sl.structType}, args...)...))
+}
+
+// removeShadowedFields applies Go's rules for promoted fields to fields with
+// the same column name: the field at the shallowest embedding depth is kept,
+// shadowing any more deeply embedded fields. If more than one field remains,
+// the column name is ambiguous and the struct is invalid.
+func (sl *structLayout) removeShadowedFields() {
+	type fieldKey struct {
+		collection *collection
+		name       string
+	}
+
+	minDepth := make(map[fieldKey]int)
+	count := make(map[fieldKey]int)
+
+	for _, f := range sl.fields {
+		k := fieldKey{f.Collection, f.Name}
+
+		depth, ok := minDepth[k]
+		if !ok || f.Depth < depth {
+			minDepth[k] = f.Depth
+			count[k] = 1
+		} else if f.Depth == depth {
+			count[k]++
+		}
+	}
+
+	fields := sl.fields[:0]
+	for _, f := range sl.fields {
+		k := fieldKey{f.Collection, f.Name}
+		if f.Depth != minDepth[k] {
+			continue
+		}
+
+		if count[k] > 1 {
+			sl.invalid("%d fields map to column '%s'", count[k], f.Name)
+			count[k] = 0
+		}
+
+		fields = append(fields, f)
+	}
+
+	sl.fields = fields
+}
+
+// findKeyFields finds the fields identifying distinct elements of coll, or of
+// the root struct if coll is nil. These are the fields tagged with the `pk`
+// option, or all fields of the element if there are none.
+func (sl *structLayout) findKeyFields(coll *collection) {
+	var all, pk []*field
+
+	for i := range sl.fields {
+		f := &sl.fields[i]
+		if f.Collection != coll {
+			continue
+		}
+
+		all = append(all, f)
+		if f.PrimaryKey {
+			pk = append(pk, f)
+		}
+	}
+
+	if len(pk) > 0 {
+		sl.keyFields[coll] = pk
+	} else {
+		sl.keyFields[coll] = all
+	}
+}
+
+// childCollections returns the collections directly within elements of coll,
+// or within the root struct if coll is nil.
+func (sl *structLayout) childCollections(coll *collection) []*collection {
+	if coll == nil {
+		return sl.rootCollections
+	}
+	return coll.Children
+}
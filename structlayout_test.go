@@ -0,0 +1,241 @@
+package structscanner
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStructLayout(t *testing.T) {
+
+	t.Run("flattens untagged embedded structs", func(t *testing.T) {
+
+		type Timestamps struct {
+			CreatedAt time.Time `db:"created_at"`
+			UpdatedAt time.Time `db:"updated_at"`
+		}
+
+		type AuditFields struct {
+			CreatedBy string `db:"created_by"`
+		}
+
+		type TestStruct struct {
+			Timestamps
+			*AuditFields
+			ID int64 `db:"id"`
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{}, ".")
+
+		for name, expected := range map[string][]int{
+			"id":         {2},
+			"created_at": {0, 0},
+			"updated_at": {0, 1},
+			"created_by": {1, 0},
+		} {
+			f := sl.fieldsByName[name]
+			if f == nil {
+				t.Errorf("Expected field for '%s' but found none", name)
+				continue
+			}
+
+			if actual := f.Indices; !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Expected field for '%s' to have indices %v but got %v", name, expected, actual)
+			}
+		}
+	})
+
+	t.Run("flattens fields with the inline option", func(t *testing.T) {
+
+		type Address struct {
+			Street string `db:"street"`
+		}
+
+		type TestStruct struct {
+			Address Address `db:",inline"`
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{}, ".")
+
+		if f := sl.fieldsByName["street"]; f == nil {
+			t.Errorf("Expected field for 'street' but found none")
+		}
+	})
+
+	t.Run("shadows embedded fields with shallower fields of the same name", func(t *testing.T) {
+
+		type Embedded struct {
+			ID   int64  `db:"id"`
+			Name string `db:"name"`
+		}
+
+		type TestStruct struct {
+			Embedded
+			ID string `db:"id"`
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{}, ".")
+
+		if expected, actual := []int{1}, sl.fieldsByName["id"].Indices; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected 'id' to have indices %v but got %v", expected, actual)
+		}
+		if expected, actual := []int{0, 1}, sl.fieldsByName["name"].Indices; !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Expected 'name' to have indices %v but got %v", expected, actual)
+		}
+	})
+
+	t.Run("is invalid when embedded fields at the same depth are ambiguous", func(t *testing.T) {
+
+		type A struct {
+			Name string `db:"name"`
+		}
+
+		type B struct {
+			Name string `db:"name"`
+		}
+
+		type TestStruct struct {
+			A
+			B
+		}
+
+		sl := newStructLayout(reflect.TypeOf(TestStruct{}), &Mapper{}, ".")
+
+		if !errors.Is(sl.err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", sl.err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+
+	t.Run("succeeds for valid struct", func(t *testing.T) {
+
+		type TestStruct struct {
+			ID     int64  `db:"id"`
+			Name   string `db:"name"`
+			Nested struct {
+				ID int64 `db:"id"`
+			} `db:"nested"`
+			untagged chan int
+		}
+
+		err := Validate(&TestStruct{})
+		if err != nil {
+			t.Errorf("Expected success but got error: %v", err)
+		}
+	})
+
+	t.Run("returns error when two fields have the same column name", func(t *testing.T) {
+
+		type TestStruct struct {
+			ID      int64 `db:"id"`
+			OtherID int64 `db:"id"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Fatalf("Expected ErrInvalidStruct but got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "column 'id'") {
+			t.Errorf("Expected error to describe column 'id' but got: %v", err)
+		}
+	})
+
+	t.Run("returns error for tagged unexported fields", func(t *testing.T) {
+
+		type TestStruct struct {
+			id int64 `db:"id"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Fatalf("Expected ErrInvalidStruct but got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "TestStruct.id") {
+			t.Errorf("Expected error to describe field 'id' but got: %v", err)
+		}
+	})
+
+	t.Run("returns error for fields of unsupported kinds", func(t *testing.T) {
+
+		type TestStruct struct {
+			Channel  chan int `db:"channel"`
+			Function func()   `db:"function"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Fatalf("Expected ErrInvalidStruct but got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "Channel") || !strings.Contains(err.Error(), "Function") {
+			t.Errorf("Expected error to describe both fields but got: %v", err)
+		}
+	})
+
+	t.Run("returns ErrUnsupportedDestination for non-struct types", func(t *testing.T) {
+
+		err := Validate(make(chan int))
+
+		if !errors.Is(err, ErrUnsupportedDestination) {
+			t.Errorf("Expected ErrUnsupportedDestination but got: %v", err)
+		}
+	})
+	t.Run("returns error for rest fields of the wrong type", func(t *testing.T) {
+
+		type TestStruct struct {
+			Rest map[string]int `db:",rest"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", err)
+		}
+	})
+	t.Run("returns error for prefix maps of the wrong type", func(t *testing.T) {
+
+		type TestStruct struct {
+			Attrs map[int]string `db:"attr,prefixmap"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", err)
+		}
+	})
+	t.Run("returns error for split fields of the wrong type", func(t *testing.T) {
+
+		type TestStruct struct {
+			Tags   string     `db:"tags,split=|"`
+			Values []chan int `db:"values,split=|"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", err)
+		}
+	})
+	t.Run("returns error for composite fields of the wrong type", func(t *testing.T) {
+
+		type TestStruct struct {
+			Name   string `db:"name,composite"`
+			Nested struct {
+				Values map[string]int `db:"values"`
+			} `db:"nested,composite"`
+		}
+
+		err := Validate(&TestStruct{})
+
+		if !errors.Is(err, ErrInvalidStruct) {
+			t.Errorf("Expected ErrInvalidStruct but got: %v", err)
+		}
+	})
+}
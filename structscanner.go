@@ -1,8 +1,8 @@
 package structscanner
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
 	"reflect"
 	"strings"
 )
@@ -11,21 +11,65 @@ import (
 // and can be used to scan database rows to struct instances.
 //
 // Struct fields with a `db:` tag are mapped using the name specified in the tag
-// as the column name. Only fields with `db:` tags are mapped.
+// as the column name. Untagged fields are not mapped, unless the Mapper has a
+// NameMapper, in which case exported untagged fields are mapped using the name
+// it returns. Fields tagged with "-" are never mapped. Alternative
+// column names may be accepted for a field with `alias=` tag options, such as
+// `db:"customer_id,alias=cust_id"`, but only one of them may appear in a query.
+//
+// The fields of untagged embedded structs, and of struct fields with the
+// `inline` tag option, are mapped as if they belonged to the parent struct,
+// following Go's rules for promoted fields where names conflict.
+//
+// A map field with the `prefixmap` tag option, such as
+// `db:"attr,prefixmap"` on a map[string]string, collects the values of all
+// columns beginning with its name and the separator, keyed by the rest of the
+// column name. NULL values are left out of the map.
+//
+// Fields with the `json` tag option, such as `db:"meta,json"`, are decoded from
+// the column with encoding/json, and may be of any type. Errors decoding a
+// value are returned as a MappingError.
+//
+// Fields with the `split=` tag option, such as `db:"tags,split=|"`, are slices
+// filled from a string delimited by the given separator. Elements may be
+// strings, numbers, booleans, times or types implementing sql.Scanner. NULL and
+// empty strings give a nil slice.
+//
+// Other slice fields, such as []string, []*int64 or [][]int32, are filled from
+// Postgres array literals, such as {1,NULL,3}. NULL elements are set to the
+// zero value of the element type.
+//
+// Struct fields with the `composite` tag option, such as
+// `db:"person,composite"`, are filled from a Postgres row literal, such as
+// (1,"Alice",t), whose elements are assigned to the struct's tagged fields in
+// declared order.
+//
+// A StructScanner may also be created for a scalar type, such as int64, string,
+// time.Time or a type implementing sql.Scanner. The first column of each row
+// is then scanned directly into the destination, without using its name.
 //
 // NULL values from the database are mapped to zero values on the struct.
 //
 // A StructScanner is not safe for concurrent use by multiple Goroutines.
 type StructScanner struct {
-	prefix          string
+	mapper          *Mapper
+	config          config
 	layout          *structLayout
 	mappedFields    []*field
 	mappedFieldPtrs []interface{}
+	mappedKeys      []string
+	columns         []string
+	row             int
 }
 
+// columnWithoutPrefix returns the normalised form of a column name, with the
+// normalised prefix removed.
 func (s *StructScanner) columnWithoutPrefix(name string) string {
-	if strings.HasPrefix(name, s.prefix+".") {
-		return name[len(s.prefix)+1:]
+	name = s.mapper.normalise(name)
+	prefix := s.mapper.normalise(s.config.prefix + s.config.separator)
+
+	if strings.HasPrefix(name, prefix) {
+		return name[len(prefix):]
 	}
 	return name
 }
@@ -42,24 +86,91 @@ func (s *StructScanner) mapColumns(rows *sql.Rows) error {
 
 	s.mappedFieldPtrs = make([]interface{}, len(columns))
 	s.mappedFields = make([]*field, len(columns))
+	s.mappedKeys = make([]string, len(columns))
+	s.columns = columns
+
+	mappedColumns := make(map[*field]string)
 
 	for i := range columns {
-		f := s.layout.fieldsByName[s.columnWithoutPrefix(columns[i])]
+		var f *field
+		if s.layout.scalar {
+			if i == 0 {
+				f = &s.layout.fields[0]
+			}
+		} else {
+			f = s.layout.fieldsByName[s.columnWithoutPrefix(columns[i])]
+		}
+
+		if f == nil && !s.layout.scalar {
+			f, s.mappedKeys[i] = s.prefixMapFor(columns[i])
+		}
+
+		if f == nil && s.layout.restField != nil {
+			f = s.layout.restField
+			s.mappedKeys[i] = columns[i]
+		}
+
 		if f == nil {
-			if !ignoreNonexistentFields {
-				panic(fmt.Sprintf("no destination field for '%s'", columns[i]))
+			if !s.config.ignoreUnknownColumns {
+				s.mappedFields = nil
+				return &MappingError{
+					Column: columns[i],
+					Prefix: s.config.prefix,
+					Type:   s.layout.structType,
+					Row:    s.row,
+					Err:    ErrNoDestinationField,
+				}
 			}
 
 			f = unknownField
+
+		} else if column, ok := mappedColumns[f]; ok && column != columns[i] && !f.Rest && !f.PrefixMap {
+			s.mappedFields = nil
+			return &MappingError{
+				Column: columns[i],
+				Prefix: s.config.prefix,
+				Type:   s.layout.structType,
+				Row:    s.row,
+				Err:    ErrColumnConflict,
+			}
 		}
 
-		s.mappedFieldPtrs[i] = reflect.New(reflect.PointerTo(f.Type)).Interface()
+		mappedColumns[f] = columns[i]
+
+		s.mappedFieldPtrs[i] = reflect.New(reflect.PointerTo(f.scanType())).Interface()
 		s.mappedFields[i] = f
 	}
 
 	return nil
 }
 
+// prefixMapFor returns the field with the `prefixmap` tag option that a column
+// belongs to, if any, along with the key for the column within the map.
+func (s *StructScanner) prefixMapFor(column string) (*field, string) {
+	name := s.columnWithoutPrefix(column)
+
+	for _, f := range s.layout.prefixMaps {
+		prefix := s.mapper.normalise(f.Name + s.config.separator)
+		if strings.HasPrefix(name, prefix) {
+			return f, name[len(prefix):]
+		}
+	}
+
+	return nil, ""
+}
+
+// isNull reports whether the column mapped to f was NULL in the last row
+// scanned, or whether no column is mapped to f at all.
+func (s *StructScanner) isNull(f *field) bool {
+	for i, mapped := range s.mappedFields {
+		if mapped == f {
+			return reflect.ValueOf(s.mappedFieldPtrs[i]).Elem().IsNil()
+		}
+	}
+
+	return true
+}
+
 // Scan populates the specified struct from a database row. Fields in the struct
 // are set according to values in the row, based on the column name and the
 // `db:` tag of the field. NULL values in the row result in the corresponding
@@ -68,7 +179,14 @@ func (s *StructScanner) mapColumns(rows *sql.Rows) error {
 // Columns are mapped when a row is first scanned. It is not safe to call Scan
 // on a StructScanner with a query returning different columns after the first
 // call.
+//
+// If the struct cannot be scanned into, an error wrapping ErrInvalidStruct is
+// returned; see Validate.
 func (s *StructScanner) Scan(rows *sql.Rows, destPtr interface{}) error {
+	if s.layout.err != nil {
+		return s.layout.err
+	}
+
 	err := s.mapColumns(rows)
 	if err != nil {
 		return err
@@ -79,23 +197,162 @@ func (s *StructScanner) Scan(rows *sql.Rows, destPtr interface{}) error {
 		return err
 	}
 
-	s.setFields(destPtr)
+	err = s.setFields(destPtr)
+	if err != nil {
+		return err
+	}
+	s.row++
 
 	return nil
 }
 
-func (s *StructScanner) setFields(destPtr interface{}) {
+// ScanContext is like Scan, but returns the context's error without scanning
+// if ctx is already done.
+func (s *StructScanner) ScanContext(ctx context.Context, rows *sql.Rows, destPtr interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.Scan(rows, destPtr)
+}
+
+// setFields sets the fields of the struct pointed to by destPtr from the values
+// scanned from the current row, returning a MappingError if a value cannot be
+// decoded.
+func (s *StructScanner) setFields(destPtr interface{}) error {
 	destValue := reflect.ValueOf(destPtr).Elem()
 
+	var elems map[*collection]reflect.Value
+	if len(s.layout.collections) > 0 {
+		elems = s.newCollectionElems()
+	}
+
 	for i := range s.mappedFields {
 		mappedField := s.mappedFields[i]
 		if mappedField == unknownField {
 			continue
 		}
 
+		root := destValue
+		if mappedField.Collection != nil {
+			elem, ok := elems[mappedField.Collection]
+			if !ok {
+				continue
+			}
+			root = elem.Elem()
+		}
+
 		instanceValue := reflect.ValueOf(s.mappedFieldPtrs[i]).Elem().Elem()
-		s.setNestedField(destValue, mappedField.Indices, instanceValue)
+
+		if mappedField.Decode != nil && instanceValue.IsValid() {
+			var err error
+			instanceValue, err = mappedField.Decode(instanceValue)
+			if err != nil {
+				return &MappingError{
+					Column: s.columns[i],
+					Prefix: s.config.prefix,
+					Type:   s.layout.structType,
+					Field:  s.layout.goFieldName(mappedField),
+					Row:    s.row,
+					Err:    err,
+				}
+			}
+		}
+
+		if mappedField.Rest || mappedField.PrefixMap {
+			// NULLs are left out of prefix maps
+			if mappedField.PrefixMap && !instanceValue.IsValid() {
+				continue
+			}
+
+			s.setMapEntry(root, mappedField, s.mappedKeys[i], instanceValue)
+			continue
+		}
+
+		s.setNestedField(root, mappedField.Indices, instanceValue)
+	}
+
+	if elems != nil {
+		s.appendCollectionElems(destValue, elems)
+	}
+
+	return nil
+}
+
+// newCollectionElems instantiates a new element for each collection that has
+// at least one non-NULL column in the current row. Collections whose columns
+// are all NULL, such as from a LEFT JOIN with no match, are skipped.
+func (s *StructScanner) newCollectionElems() map[*collection]reflect.Value {
+	elems := make(map[*collection]reflect.Value)
+
+	for i := range s.mappedFields {
+		c := s.mappedFields[i].Collection
+		if c == nil || elems[c].IsValid() {
+			continue
+		}
+
+		if reflect.ValueOf(s.mappedFieldPtrs[i]).Elem().IsNil() {
+			continue
+		}
+
+		elemType := c.Type.Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+
+		elems[c] = reflect.New(elemType)
+	}
+
+	return elems
+}
+
+// appendCollectionElems appends the new collection elements to their parents,
+// working from the most deeply nested upwards so that each element is complete
+// before it is copied into its parent.
+func (s *StructScanner) appendCollectionElems(destValue reflect.Value, elems map[*collection]reflect.Value) {
+	for i := len(s.layout.collections) - 1; i >= 0; i-- {
+		c := s.layout.collections[i]
+
+		elem, ok := elems[c]
+		if !ok {
+			continue
+		}
+
+		parent := destValue
+		if c.Parent != nil {
+			parentElem, ok := elems[c.Parent]
+			if !ok {
+				continue
+			}
+			parent = parentElem.Elem()
+		}
+
+		if c.Type.Elem().Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+
+		sliceValue := fieldByIndices(parent, c.Indices)
+		sliceValue.Set(reflect.Append(sliceValue, elem))
+	}
+}
+
+// setMapEntry sets the entry for key in the map field f, instantiating the map
+// if necessary. NULL values are stored as the zero value of the map's values.
+// Byte slices are copied, as they may refer to memory owned by the driver.
+func (s *StructScanner) setMapEntry(root reflect.Value, f *field, key string, value reflect.Value) {
+	mapValue := fieldByIndices(root, f.Indices)
+	if mapValue.IsNil() {
+		mapValue.Set(reflect.MakeMap(mapValue.Type()))
 	}
+
+	if !value.IsValid() {
+		value = reflect.Zero(f.Type)
+
+	} else if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		value = reflect.AppendSlice(reflect.MakeSlice(value.Type(), 0, value.Len()), value)
+	}
+
+	mapValue.SetMapIndex(reflect.ValueOf(key), value)
 }
 
 func (s *StructScanner) setNestedField(root reflect.Value, pathIndices []int, value reflect.Value) {
@@ -133,22 +390,38 @@ func (s *StructScanner) setNestedField(root reflect.Value, pathIndices []int, va
 	}
 }
 
+// Validate checks that the struct pointed to by structPtr can be scanned into
+// using the default configuration. See Mapper.Validate.
+func Validate(structPtr interface{}) error {
+	return defaultMapper.Validate(structPtr)
+}
+
+// fieldByIndices returns the field of v at the path given by indices,
+// instantiating any nil pointers to structs along the way.
+func fieldByIndices(v reflect.Value, indices []int) reflect.Value {
+	for _, i := range indices {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return v
+}
+
 // For returns a StructScanner suitable for scanning a struct of the type
 // given by structPtr.
 //
 // A prefix may be specified; struct fields are mapped assuming that the columns
-// from the database have the specified prefix with a dot (.) separator.
-func For(structPtr interface{}, prefix string) StructScanner {
-	structType := reflect.TypeOf(structPtr)
-
-	cached, ok := cachedLayouts.Load(structType)
-	if !ok {
-		cached, _ = cachedLayouts.LoadOrStore(structType, newStructLayout(structType))
-	}
-
-	scanner := StructScanner{
-		prefix: prefix,
-		layout: cached.(*structLayout),
-	}
-	return scanner
+// from the database have the specified prefix with a dot (.) separator, or the
+// separator given with WithSeparator.
+//
+// Options may be given to override the default configuration for this
+// StructScanner.
+func For(structPtr interface{}, prefix string, opts ...Option) StructScanner {
+	return defaultMapper.For(structPtr, prefix, opts...)
 }
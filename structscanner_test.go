@@ -1,8 +1,11 @@
 package structscanner
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -335,7 +338,7 @@ func TestStructScanner(t *testing.T) {
 			}
 		})
 
-		t.Run("panics when no destination field exists to receive a value", func(t *testing.T) {
+		t.Run("returns a MappingError when no destination field exists to receive a value", func(t *testing.T) {
 			ss := For((*TestStruct)(nil), "prefix")
 
 			mockQuery := fmt.Sprintf("some query")
@@ -358,30 +361,201 @@ func TestStructScanner(t *testing.T) {
 			}
 
 			var result TestStruct
-			var panicValue any
 
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						panicValue = r
-					}
-				}()
+			err = ss.Scan(rows, &result)
+
+			if !errors.Is(err, ErrNoDestinationField) {
+				t.Fatalf("Expected ErrNoDestinationField but got: %v", err)
+			}
+
+			var mappingErr *MappingError
+			if !errors.As(err, &mappingErr) {
+				t.Fatalf("Expected a MappingError but got: %v", err)
+			}
+
+			if expected, actual := "prefix.nonexistent_field", mappingErr.Column; expected != actual {
+				t.Errorf("Expected column '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := "prefix", mappingErr.Prefix; expected != actual {
+				t.Errorf("Expected prefix '%s' but got '%s'", expected, actual)
+			}
+			if expected, actual := reflect.TypeOf(result), mappingErr.Type; expected != actual {
+				t.Errorf("Expected type %v but got %v", expected, actual)
+			}
+			if expected, actual := 0, mappingErr.Row; expected != actual {
+				t.Errorf("Expected row %d but got %d", expected, actual)
+			}
+		})
+
+		t.Run("does not scan when context is done", func(t *testing.T) {
+			ss := For((*TestStruct)(nil), "")
+
+			mockQuery := fmt.Sprintf("some query")
+
+			dbMock.ExpectQuery(mockQuery).WillReturnRows(
+				sqlmock.NewRows([]string{
+					"string_value",
+				}).AddRow(
+					"string value",
+				),
+			)
+
+			rows, err := db.Query(mockQuery)
+			if err != nil {
+				t.Fatalf("Error executing query: %v", err)
+			}
+			defer rows.Close()
+
+			if !rows.Next() {
+				t.Fatalf("Expected one row but got none")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			var result TestStruct
+
+			err = ss.ScanContext(ctx, rows, &result)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled but got: %v", err)
+			}
+
+			if expected, actual := "", result.StringValue; expected != actual {
+				t.Errorf("Expected string value to be unset but got '%s'", actual)
+			}
+		})
+
+		t.Run("instantiates embedded pointer structs when a field is set to non-NULL", func(t *testing.T) {
+
+			type Embedded struct {
+				Value string `db:"value"`
+			}
+
+			var result struct {
+				*Embedded
+			}
+
+			ss := For(&result, "")
 
-				_ = ss.Scan(rows, &result)
-			}()
+			mockQuery := fmt.Sprintf("some query")
+
+			dbMock.ExpectQuery(mockQuery).WillReturnRows(
+				sqlmock.NewRows([]string{
+					"value",
+				}).AddRow(
+					"embedded value",
+				),
+			)
+
+			rows, err := db.Query(mockQuery)
+			if err != nil {
+				t.Fatalf("Error executing query: %v", err)
+			}
+			defer rows.Close()
 
-			if panicValue == nil {
-				t.Fatalf("Expected a panic but returned from Scan")
+			if !rows.Next() {
+				t.Fatalf("Expected one row but got none")
 			}
 
-			switch v := panicValue.(type) {
-			case string:
-				if expected, actual := "no destination field for 'prefix.nonexistent_field'", v; expected != actual {
-					t.Errorf("Expected panic with '%s' but was '%s'", expected, actual)
+			err = ss.Scan(rows, &result)
+			if err != nil {
+				t.Fatalf("Expected success but got error: %v", err)
+			}
+
+			if result.Embedded == nil {
+				t.Fatalf("Expected embedded struct pointer to have been initialised but wasn’t")
+			}
+			if expected, actual := "embedded value", result.Value; expected != actual {
+				t.Errorf("Expected embedded value '%s' but got '%s'", expected, actual)
+			}
+		})
+
+		t.Run("maps fields from aliased columns", func(t *testing.T) {
+
+			type AliasStruct struct {
+				CustomerID int64 `db:"customer_id,alias=cust_id,alias=customerid"`
+			}
+
+			for _, column := range []string{"customer_id", "cust_id", "customerid"} {
+				ss := For((*AliasStruct)(nil), "")
+
+				mockQuery := fmt.Sprintf("some query")
+
+				dbMock.ExpectQuery(mockQuery).WillReturnRows(
+					sqlmock.NewRows([]string{
+						column,
+					}).AddRow(
+						123,
+					),
+				)
+
+				rows, err := db.Query(mockQuery)
+				if err != nil {
+					t.Fatalf("Error executing query: %v", err)
+				}
+
+				if !rows.Next() {
+					t.Fatalf("Expected one row but got none")
+				}
+
+				var result AliasStruct
+
+				err = ss.Scan(rows, &result)
+				if err != nil {
+					t.Fatalf("Expected success for column '%s' but got error: %v", column, err)
+				}
+
+				if expected, actual := int64(123), result.CustomerID; expected != actual {
+					t.Errorf("Expected value %d for column '%s' but got %d", expected, column, actual)
 				}
 
-			default:
-				t.Fatalf("Panicked with unexpected value %v", panicValue)
+				rows.Close()
+			}
+		})
+
+		t.Run("returns a MappingError when more than one alias of a field is queried", func(t *testing.T) {
+
+			type AliasStruct struct {
+				CustomerID int64 `db:"customer_id,alias=cust_id"`
+			}
+
+			ss := For((*AliasStruct)(nil), "")
+
+			mockQuery := fmt.Sprintf("some query")
+
+			dbMock.ExpectQuery(mockQuery).WillReturnRows(
+				sqlmock.NewRows([]string{
+					"customer_id",
+					"cust_id",
+				}).AddRow(
+					123,
+					456,
+				),
+			)
+
+			rows, err := db.Query(mockQuery)
+			if err != nil {
+				t.Fatalf("Error executing query: %v", err)
+			}
+			defer rows.Close()
+
+			if !rows.Next() {
+				t.Fatalf("Expected one row but got none")
+			}
+
+			var result AliasStruct
+
+			err = ss.Scan(rows, &result)
+
+			if !errors.Is(err, ErrColumnConflict) {
+				t.Fatalf("Expected ErrColumnConflict but got: %v", err)
+			}
+
+			var mappingErr *MappingError
+			if errors.As(err, &mappingErr) {
+				if expected, actual := "cust_id", mappingErr.Column; expected != actual {
+					t.Errorf("Expected column '%s' but got '%s'", expected, actual)
+				}
 			}
 		})
 
@@ -0,0 +1,51 @@
+package structscanner
+
+import "strings"
+
+// tagOptions holds the comma-separated options following the column name in a
+// struct tag.
+type tagOptions []string
+
+// parseTag splits a struct tag into a column name and its options.
+//
+// As options are separated by commas, a comma is accepted as the value of an
+// option of the form key=value by following it with an empty option, as in
+// `db:"tags,split=,"`.
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, found := strings.Cut(tag, ",")
+	if !found {
+		return name, nil
+	}
+
+	var options tagOptions
+	for _, o := range strings.Split(opts, ",") {
+		if n := len(options); o == "" && n > 0 && strings.HasSuffix(options[n-1], "=") {
+			options[n-1] += ","
+			continue
+		}
+		options = append(options, o)
+	}
+
+	return name, options
+}
+
+// values returns the values of all options of the form key=value.
+func (o tagOptions) values(key string) []string {
+	var values []string
+	for _, o := range o {
+		if value, ok := strings.CutPrefix(o, key+"="); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// has reports whether the options contain opt.
+func (o tagOptions) has(opt string) bool {
+	for _, o := range o {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}